@@ -0,0 +1,129 @@
+//go:build sdl
+// +build sdl
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// SDL audio output. Samples are queued once per frame and resampled to keep
+// the queue close to audioTargetLatency, without a device samples are dropped.
+type Audio struct {
+	device sdl.AudioDeviceID
+	// false when no audio device could be opened
+	available  bool
+	enabled    bool
+	volume     float32
+	sampleRate int
+	resampler  Resampler
+}
+
+// Returns the SDL audio output at sampleRate, or nil when no audio device can be opened
+func newSdlAudio(sampleRate int) AudioOutput {
+	audio := &Audio{}
+	audio.init(sampleRate)
+	if !audio.available {
+		return nil
+	}
+	return audio
+}
+
+// Target duration of the queued audio
+const audioTargetLatency time.Duration = 60 * time.Millisecond
+
+// Volume step of the frontend volume controls
+const volumeStep float32 = 0.1
+
+// Open the default audio device at sampleRate, or at the closest rate it supports
+func (audio *Audio) init(sampleRate int) {
+	audio.enabled = true
+	audio.volume = 1
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize audio, sound disabled: %s\n", err)
+		return
+	}
+	desired := sdl.AudioSpec{
+		Freq:     int32(sampleRate),
+		Format:   sdl.AUDIO_F32SYS,
+		Channels: 2,
+		Samples:  1024,
+	}
+	var obtained sdl.AudioSpec
+	device, err := sdl.OpenAudioDevice("", false, &desired, &obtained, sdl.AUDIO_ALLOW_FREQUENCY_CHANGE)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open audio device, sound disabled: %s\n", err)
+		return
+	}
+	audio.device = device
+	audio.sampleRate = int(obtained.Freq)
+	audio.available = true
+	sdl.PauseAudioDevice(audio.device, false)
+}
+
+// Returns the number of stereo frames waiting to be played
+func (audio *Audio) queued() int {
+	return int(sdl.GetQueuedAudioSize(audio.device)) / 8
+}
+
+// Queue interleaved stereo samples. When the queue is well above its target,
+// this waits for it to drain, so emulation runs at the audio device speed.
+func (audio *Audio) queue(samples []float32) {
+	if !audio.available || len(samples) == 0 {
+		return
+	}
+	target := int(audioTargetLatency.Seconds() * float64(audio.sampleRate))
+	output := audio.resampler.resample(samples, rateRatio(audio.queued(), target))
+	volume := audio.volume
+	// muted audio is still queued to keep the emulation speed
+	if !audio.enabled {
+		volume = 0
+	}
+	for i := range output {
+		output[i] *= volume
+	}
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&output[0])), len(output)*4)
+	if err := sdl.QueueAudio(audio.device, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to queue audio: %s\n", err)
+	}
+	for audio.queued() > target*2 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Queue samples, see queue
+func (audio *Audio) WriteSamples(samples []float32) {
+	audio.queue(samples)
+}
+
+// Returns the sample rate of the device
+func (audio *Audio) outputRate() int {
+	return audio.sampleRate
+}
+
+// Mute or unmute the audio
+func (audio *Audio) toggle() {
+	audio.enabled = !audio.enabled
+}
+
+// Set the volume, between 0 and 1
+func (audio *Audio) setVolume(volume float32) {
+	if volume < 0 {
+		volume = 0
+	} else if volume > 1 {
+		volume = 1
+	}
+	audio.volume = volume
+}
+
+func (audio *Audio) close() {
+	if audio.available {
+		sdl.CloseAudioDevice(audio.device)
+		audio.available = false
+	}
+}
@@ -1,26 +1,135 @@
+//go:build sdl
+// +build sdl
+
 package main
 
 import (
+	"errors"
 	"fmt"
+	"image/color"
 	"os"
 
+	"example/gameboy/gb"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// SDL window frontend, with an optional VRAM viewer window
 type Display struct {
 	window       *sdl.Window
 	renderer     *sdl.Renderer
 	vramWindow   *sdl.Window
 	vramRenderer *sdl.Renderer
 	running      bool
+	paused       bool
+	// size of a Game Boy pixel on screen
+	scale int
+	// input bindings from keyboard keys and controller buttons to joypad buttons
+	keyBindings        map[sdl.Keycode]gb.Buttons
+	controllerBindings map[uint8]gb.Buttons
+	controllers        []*sdl.GameController
+	// the controllers are rumbling
+	rumbling bool
+	// joypad buttons currently pressed
+	buttons gb.Buttons
+	// audio output controlled with the audio hotkeys, may be nil
+	audio *Audio
+	// emulator shown in the VRAM viewer, nil without the viewer
+	emu *gb.Emulator
+}
+
+// Returns the SDL frontend of emu, controlling audio with the audio hotkeys
+func newSdlFrontend(options Options, emu *gb.Emulator, audio AudioOutput) (Frontend, error) {
+	display := &Display{paused: options.paused}
+	if display.init(options.scale) != 0 {
+		return nil, errors.New("cannot open the SDL window")
+	}
+	if options.debug {
+		if display.initVramViewer() != 0 {
+			display.close()
+			return nil, errors.New("cannot open the SDL VRAM viewer window")
+		}
+		display.emu = emu
+	}
+	display.audio, _ = audio.(*Audio)
+	if err := display.bindOptions(options); err != nil {
+		display.close()
+		return nil, err
+	}
+	return display, nil
+}
+
+// Apply the bindings of the -keys and -controller options. A joypad button
+// given in the options loses its default bindings.
+func (display *Display) bindOptions(options Options) error {
+	keys := make(map[sdl.Keycode]gb.Buttons)
+	for name, button := range options.keys {
+		key := sdl.GetKeyFromName(name)
+		if key == sdl.K_UNKNOWN {
+			return fmt.Errorf("unknown key %q", name)
+		}
+		keys[key] = button
+	}
+	controllerButtons := make(map[uint8]gb.Buttons)
+	for name, button := range options.controllerButtons {
+		controllerButton := sdl.GameControllerGetButtonFromString(name)
+		if controllerButton == sdl.CONTROLLER_BUTTON_INVALID {
+			return fmt.Errorf("unknown game controller button %q", name)
+		}
+		controllerButtons[uint8(controllerButton)] = button
+	}
+	for key, button := range defaultKeyBindings {
+		for _, bound := range keys {
+			if bound == button {
+				delete(display.keyBindings, key)
+			}
+		}
+	}
+	for controllerButton, button := range defaultControllerBindings {
+		for _, bound := range controllerButtons {
+			if bound == button {
+				delete(display.controllerBindings, controllerButton)
+			}
+		}
+	}
+	for key, button := range keys {
+		display.bindKey(key, button)
+	}
+	for controllerButton, button := range controllerButtons {
+		display.bindControllerButton(controllerButton, button)
+	}
+	return nil
 }
 
-const tile_size int = 4
+// Duration of a controller rumble, in milliseconds, longer than a frame
+const rumbleDuration uint32 = 100
+
+var defaultKeyBindings map[sdl.Keycode]gb.Buttons = map[sdl.Keycode]gb.Buttons{
+	sdl.K_RIGHT:     gb.ButtonRight,
+	sdl.K_LEFT:      gb.ButtonLeft,
+	sdl.K_UP:        gb.ButtonUp,
+	sdl.K_DOWN:      gb.ButtonDown,
+	sdl.K_x:         gb.ButtonA,
+	sdl.K_z:         gb.ButtonB,
+	sdl.K_BACKSPACE: gb.ButtonSelect,
+	sdl.K_RETURN:    gb.ButtonStart,
+}
+
+var defaultControllerBindings map[uint8]gb.Buttons = map[uint8]gb.Buttons{
+	sdl.CONTROLLER_BUTTON_DPAD_RIGHT: gb.ButtonRight,
+	sdl.CONTROLLER_BUTTON_DPAD_LEFT:  gb.ButtonLeft,
+	sdl.CONTROLLER_BUTTON_DPAD_UP:    gb.ButtonUp,
+	sdl.CONTROLLER_BUTTON_DPAD_DOWN:  gb.ButtonDown,
+	sdl.CONTROLLER_BUTTON_A:          gb.ButtonA,
+	sdl.CONTROLLER_BUTTON_B:          gb.ButtonB,
+	sdl.CONTROLLER_BUTTON_BACK:       gb.ButtonSelect,
+	sdl.CONTROLLER_BUTTON_START:      gb.ButtonStart,
+}
 
-func (display *Display) init() int {
+func (display *Display) init(scale int) int {
 	var err error
+	display.scale = scale
 	display.window, err = sdl.CreateWindow("GB", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		160*int32(tile_size), 144*int32(tile_size), sdl.WINDOW_SHOWN)
+		160*int32(scale), 144*int32(scale), sdl.WINDOW_SHOWN)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create window: %s\n", err)
 		return 1
@@ -30,10 +139,118 @@ func (display *Display) init() int {
 		fmt.Fprintf(os.Stderr, "Failed to create renderer: %s\n", err)
 		return 2
 	}
+	display.keyBindings = make(map[sdl.Keycode]gb.Buttons)
+	for key, button := range defaultKeyBindings {
+		display.keyBindings[key] = button
+	}
+	display.controllerBindings = make(map[uint8]gb.Buttons)
+	for controllerButton, button := range defaultControllerBindings {
+		display.controllerBindings[controllerButton] = button
+	}
+	// controllers connected at startup are reported as CONTROLLERDEVICEADDED events
+	if err = sdl.InitSubSystem(sdl.INIT_GAMECONTROLLER); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize game controllers: %s\n", err)
+	}
 	display.running = true
 	return 0
 }
 
+// Bind keyboard key to joypad button, replacing any previous binding of key
+func (display *Display) bindKey(key sdl.Keycode, button gb.Buttons) {
+	display.keyBindings[key] = button
+}
+
+// Bind game controller button to joypad button, replacing any previous binding of controllerButton
+func (display *Display) bindControllerButton(controllerButton uint8, button gb.Buttons) {
+	display.controllerBindings[controllerButton] = button
+}
+
+// Poll SDL events and update the buttons pressed, returns false once the window is closed
+func (display *Display) handleEvents() bool {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			println("Quit")
+			display.running = false
+		case *sdl.KeyboardEvent:
+			if e.Type == sdl.KEYDOWN && e.Keysym.Sym == sdl.K_p {
+				display.paused = !display.paused
+			}
+			if e.Type == sdl.KEYDOWN && display.audio != nil {
+				display.handleAudioKey(e.Keysym.Sym)
+			}
+			if button, ok := display.keyBindings[e.Keysym.Sym]; ok && e.Repeat == 0 {
+				display.setButton(button, e.Type == sdl.KEYDOWN)
+			}
+		case *sdl.ControllerDeviceEvent:
+			if e.Type == sdl.CONTROLLERDEVICEADDED {
+				if controller := sdl.GameControllerOpen(int(e.Which)); controller != nil {
+					display.controllers = append(display.controllers, controller)
+				}
+			}
+		case *sdl.ControllerButtonEvent:
+			if button, ok := display.controllerBindings[e.Button]; ok {
+				display.setButton(button, e.State == sdl.PRESSED)
+			}
+		}
+	}
+	return display.running
+}
+
+// Returns true while paused with P
+func (display *Display) isPaused() bool {
+	return display.paused
+}
+
+// Returns the buttons currently pressed
+func (display *Display) Buttons() gb.Buttons {
+	return display.buttons
+}
+
+// Draw frame, and the VRAM tiles when the viewer is open
+func (display *Display) WriteFrame(frame gb.Frame) {
+	display.display(frame)
+	if display.emu != nil {
+		display.displayVram(display.emu.VramTiles())
+	}
+}
+
+// Rumble the game controllers while on. The rumble is renewed every frame, and
+// stops by itself when frames stop.
+func (display *Display) setRumble(on bool) {
+	if !on && !display.rumbling {
+		return
+	}
+	display.rumbling = on
+	var strength uint16
+	if on {
+		strength = 0xFFFF
+	}
+	for _, controller := range display.controllers {
+		controller.Rumble(strength, strength, rumbleDuration)
+	}
+}
+
+func (display *Display) setButton(button gb.Buttons, pressed bool) {
+	if pressed {
+		display.buttons |= button
+	} else {
+		display.buttons &^= button
+	}
+}
+
+// Audio hotkeys: M toggles the sound, - and = change the volume
+func (display *Display) handleAudioKey(key sdl.Keycode) {
+	switch key {
+	case sdl.K_m:
+		display.audio.toggle()
+	case sdl.K_MINUS:
+		display.audio.setVolume(display.audio.volume - volumeStep)
+	case sdl.K_EQUALS:
+		display.audio.setVolume(display.audio.volume + volumeStep)
+	}
+}
+
 func (display *Display) initVramViewer() int {
 	var err error
 	display.vramWindow, err = sdl.CreateWindow("GB", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
@@ -51,59 +268,50 @@ func (display *Display) initVramViewer() int {
 	return 0
 }
 
-func (display *Display) display(gpu Gpu) int {
-
-	display.running = true
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch event.(type) {
-		case *sdl.QuitEvent:
-			println("Quit")
-			display.running = false
-		}
-	}
-	if gpu.rendering {
-		display.renderer.SetDrawColor(255, 255, 255, 0)
-		display.renderer.Clear()
-		for x := 1; x < 160*tile_size; x++ {
-			for y := 1; y < 144*tile_size; y++ {
-				if gpu.frame_buffer[x/tile_size][y/tile_size] > 0 {
-					display.renderer.SetDrawColor(0, 0, 0, 255)
-					display.renderer.DrawPoint(int32(x), int32(y))
-				}
+func (display *Display) display(frame gb.Frame) int {
+	display.renderer.SetDrawColor(255, 255, 255, 0)
+	display.renderer.Clear()
+	for x := 1; x < gb.ScreenWidth*display.scale; x++ {
+		for y := 1; y < gb.ScreenHeight*display.scale; y++ {
+			if shade := frame[y/display.scale][x/display.scale]; shade > 0 {
+				gray := gb.Palette[shade&0x03].(color.Gray)
+				display.renderer.SetDrawColor(gray.Y, gray.Y, gray.Y, 255)
+				display.renderer.DrawPoint(int32(x), int32(y))
 			}
 		}
-		display.renderer.Present()
 	}
+	display.renderer.Present()
 	return 0
 }
 
-func (display *Display) displayVram(gpu Gpu, mem Memory) int {
-
-	display.running = true
-	vram := gpu.getVram(mem)
-	if gpu.rendering {
-		display.vramRenderer.SetDrawColor(255, 255, 255, 0)
-		display.vramRenderer.Clear()
-		var screenx int = 0
-		for y, slice := range vram {
-			if y%(8*16) == 0 && y != 0 {
-				screenx += 8
-			}
-			for x, pixel := range slice {
-				if pixel > 0 {
-					display.vramRenderer.SetDrawColor(0, 0, 0, 255)
-					display.vramRenderer.DrawPoint(int32(screenx+x), int32(y%(8*16)))
-				}
+func (display *Display) displayVram(vram [512 * 8][8]byte) int {
+	display.vramRenderer.SetDrawColor(255, 255, 255, 0)
+	display.vramRenderer.Clear()
+	var screenx int = 0
+	for y, slice := range vram {
+		if y%(8*16) == 0 && y != 0 {
+			screenx += 8
+		}
+		for x, pixel := range slice {
+			if pixel > 0 {
+				display.vramRenderer.SetDrawColor(0, 0, 0, 255)
+				display.vramRenderer.DrawPoint(int32(screenx+x), int32(y%(8*16)))
 			}
 		}
-		display.vramRenderer.Present()
 	}
+	display.vramRenderer.Present()
 	return 0
 }
 
 func (display *Display) close() {
+	for _, controller := range display.controllers {
+		controller.Close()
+	}
 	display.renderer.Destroy()
 	display.window.Destroy()
+	if display.vramRenderer != nil {
+		display.vramClose()
+	}
 }
 
 func (display *Display) vramClose() {
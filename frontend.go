@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"example/gameboy/gb"
+)
+
+// Window, or headless, frontend showing the frames and reading the buttons
+type Frontend interface {
+	gb.VideoSink
+	gb.InputSource
+	// Handle pending events, returns false once the emulator must stop
+	handleEvents() bool
+	// Returns true while emulation is paused
+	isPaused() bool
+	// Turn the rumble of the controllers on or off, called after each frame
+	setRumble(on bool)
+	close()
+}
+
+// Audio device playing the samples
+type AudioOutput interface {
+	gb.AudioSink
+	// Returns the sample rate of the device
+	outputRate() int
+	close()
+}
+
+// Frontend without window nor input, stopping on Ctrl-C so the save file is written
+type Headless struct {
+	interrupt chan os.Signal
+}
+
+func newHeadless() *Headless {
+	headless := &Headless{interrupt: make(chan os.Signal, 1)}
+	signal.Notify(headless.interrupt, os.Interrupt)
+	return headless
+}
+
+func (headless *Headless) WriteFrame(frame gb.Frame) {}
+
+func (headless *Headless) Buttons() gb.Buttons {
+	return 0
+}
+
+func (headless *Headless) handleEvents() bool {
+	select {
+	case <-headless.interrupt:
+		return false
+	default:
+		return true
+	}
+}
+
+func (headless *Headless) isPaused() bool {
+	return false
+}
+
+func (headless *Headless) setRumble(on bool) {}
+
+func (headless *Headless) close() {
+	signal.Stop(headless.interrupt)
+}
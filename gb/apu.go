@@ -0,0 +1,284 @@
+package gb
+
+import "math"
+
+// Audio processing unit, mapped at 0xFF10-0xFF3F
+// see https://gbdev.io/pandocs/Audio.html
+type Apu struct {
+	enabled bool
+	square1 Square
+	square2 Square
+	wave    Wave
+	noise   Noise
+	// NR50 and NR51, master volume and channel panning
+	volume  byte
+	panning byte
+	// last written value of each register, for reads
+	registers [0x20]byte
+	waveRam   [16]byte
+	// 512 Hz frame sequencer step, clocked by falling edges of DIV bit 4
+	sequencerStep int
+	divBit        bool
+	// output samples per second, no samples are produced when 0
+	sampleRate int
+	// incremented by sampleRate every cycle, a sample is produced every cpuFrequency
+	sampleClock int
+	// interleaved left and right samples, not read yet
+	samples []float32
+	// high-pass filter removing the DC offset of the DACs
+	capacitorLeft  float32
+	capacitorRight float32
+	charge         float32
+}
+
+// Cycles per second
+const cpuFrequency int = 4194304
+
+// Bits read back as 1 for each register in 0xFF10-0xFF2F, write-only bits and
+// unused registers read as 1
+var apuReadMasks [0x20]byte = [0x20]byte{
+	0x80, 0x3F, 0x00, 0xFF, 0xBF,
+	0xFF, 0x3F, 0x00, 0xFF, 0xBF,
+	0x7F, 0xFF, 0x9F, 0xFF, 0xBF,
+	0xFF, 0xFF, 0x00, 0x00, 0xBF,
+	0x00, 0x00, 0x70,
+	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+}
+
+// Length counter, disabling its channel when it expires
+type Length struct {
+	counter int
+	enabled bool
+}
+
+// Clock the length counter, returns true when the channel must be disabled
+func (length *Length) clock() bool {
+	if !length.enabled || length.counter == 0 {
+		return false
+	}
+	length.counter--
+	return length.counter == 0
+}
+
+// Reload an expired counter when its channel is triggered
+func (length *Length) trigger(max int) {
+	if length.counter == 0 {
+		length.counter = max
+	}
+}
+
+// Volume envelope, set by NRx2
+type Envelope struct {
+	initial byte
+	up      bool
+	period  byte
+	volume  byte
+	timer   byte
+}
+
+func (envelope *Envelope) write(value byte) {
+	envelope.initial = value >> 4
+	envelope.up = hasBit(uint16(value), 3)
+	envelope.period = value & 0x07
+}
+
+func (envelope *Envelope) trigger() {
+	envelope.volume = envelope.initial
+	envelope.timer = envelope.period
+}
+
+func (envelope *Envelope) clock() {
+	if envelope.period == 0 {
+		return
+	}
+	if envelope.timer > 0 {
+		envelope.timer--
+	}
+	if envelope.timer > 0 {
+		return
+	}
+	envelope.timer = envelope.period
+	if envelope.up && envelope.volume < 15 {
+		envelope.volume++
+	} else if !envelope.up && envelope.volume > 0 {
+		envelope.volume--
+	}
+}
+
+// Set the number of stereo samples produced per second
+func (apu *Apu) setSampleRate(rate int) {
+	apu.sampleRate = rate
+	apu.sampleClock = 0
+	apu.samples = apu.samples[:0]
+	apu.charge = float32(math.Pow(0.999958, float64(cpuFrequency)/float64(rate)))
+}
+
+// Returns the samples produced since the last call, interleaved left and right
+func (apu *Apu) readSamples() []float32 {
+	samples := apu.samples
+	apu.samples = nil
+	return samples
+}
+
+// Advance the APU by the given number of cycles
+func (apu *Apu) step(cycles int, mem *Memory) {
+	for i := 0; i < cycles; i++ {
+		if apu.enabled {
+			apu.square1.step()
+			apu.square2.step()
+			apu.wave.step(&apu.waveRam)
+			apu.noise.step()
+		}
+		if apu.sampleRate == 0 {
+			continue
+		}
+		apu.sampleClock += apu.sampleRate
+		if apu.sampleClock >= cpuFrequency {
+			apu.sampleClock -= cpuFrequency
+			apu.writeSample()
+		}
+	}
+	// DIV runs twice as fast in double speed mode, the frame sequencer keeps its rate
+	var bit uint16 = 12
+	if mem.doubleSpeed {
+		bit = 13
+	}
+	divBit := hasBit(mem.timer.counter, bit)
+	if apu.divBit && !divBit && apu.enabled {
+		apu.clockSequencer()
+	}
+	apu.divBit = divBit
+}
+
+// Clock the length counters at 256 Hz, the sweep at 128 Hz and the envelopes at 64 Hz
+func (apu *Apu) clockSequencer() {
+	if apu.sequencerStep%2 == 0 {
+		if apu.square1.length.clock() {
+			apu.square1.enabled = false
+		}
+		if apu.square2.length.clock() {
+			apu.square2.enabled = false
+		}
+		if apu.wave.length.clock() {
+			apu.wave.enabled = false
+		}
+		if apu.noise.length.clock() {
+			apu.noise.enabled = false
+		}
+	}
+	if apu.sequencerStep == 2 || apu.sequencerStep == 6 {
+		apu.square1.clockSweep()
+	}
+	if apu.sequencerStep == 7 {
+		apu.square1.envelope.clock()
+		apu.square2.envelope.clock()
+		apu.noise.envelope.clock()
+	}
+	apu.sequencerStep = (apu.sequencerStep + 1) & 0x07
+}
+
+// Returns the left and right outputs, between -1 and 1, of the channels mixed
+// according to NR50 and NR51
+func (apu *Apu) mix() (float32, float32) {
+	outputs := [4]byte{apu.square1.output(), apu.square2.output(), apu.wave.output(), apu.noise.output()}
+	dacs := [4]bool{apu.square1.dac, apu.square2.dac, apu.wave.dac, apu.noise.dac}
+	var left, right float32
+	for channel := 0; channel < 4; channel++ {
+		if !apu.enabled || !dacs[channel] {
+			continue
+		}
+		// the DACs convert 0-15 to an analog value between 1 and -1
+		analog := 1 - float32(outputs[channel])/7.5
+		if hasBit(uint16(apu.panning), uint16(channel+4)) {
+			left += analog
+		}
+		if hasBit(uint16(apu.panning), uint16(channel)) {
+			right += analog
+		}
+	}
+	left *= float32((apu.volume>>4)&0x07+1) / 32
+	right *= float32(apu.volume&0x07+1) / 32
+	return left, right
+}
+
+// Append the current output to the samples, once filtered
+func (apu *Apu) writeSample() {
+	left, right := apu.mix()
+	filteredLeft := left - apu.capacitorLeft
+	apu.capacitorLeft = left - filteredLeft*apu.charge
+	filteredRight := right - apu.capacitorRight
+	apu.capacitorRight = right - filteredRight*apu.charge
+	// drop the samples nobody reads after one second
+	if len(apu.samples) >= apu.sampleRate*2 {
+		apu.samples = apu.samples[:0]
+	}
+	apu.samples = append(apu.samples, filteredLeft, filteredRight)
+}
+
+func (apu *Apu) readByte(address uint16) byte {
+	if address >= 0xFF30 {
+		return apu.waveRam[address-0xFF30]
+	}
+	register := address - 0xFF10
+	if address == 0xFF26 {
+		value := apuReadMasks[register]
+		if apu.enabled {
+			value |= 0x80
+		}
+		for channel, enabled := range []bool{apu.square1.enabled, apu.square2.enabled, apu.wave.enabled, apu.noise.enabled} {
+			if enabled {
+				value |= 1 << channel
+			}
+		}
+		return value
+	}
+	return apu.registers[register] | apuReadMasks[register]
+}
+
+func (apu *Apu) writeByte(address uint16, value byte) {
+	if address >= 0xFF30 {
+		apu.waveRam[address-0xFF30] = value
+		return
+	}
+	if address == 0xFF26 {
+		enabled := hasBit(uint16(value), 7)
+		if apu.enabled && !enabled {
+			apu.powerOff()
+		}
+		if !apu.enabled && enabled {
+			apu.sequencerStep = 0
+		}
+		apu.enabled = enabled
+		return
+	}
+	// registers are read-only while the APU is off
+	if !apu.enabled {
+		return
+	}
+	apu.registers[address-0xFF10] = value
+	switch {
+	case address <= 0xFF14:
+		apu.square1.writeRegister(int(address-0xFF10), value)
+	case address >= 0xFF15 && address <= 0xFF19:
+		apu.square2.writeRegister(int(address-0xFF15), value)
+	case address >= 0xFF1A && address <= 0xFF1E:
+		apu.wave.writeRegister(int(address-0xFF1A), value)
+	case address >= 0xFF1F && address <= 0xFF23:
+		apu.noise.writeRegister(int(address-0xFF1F), value)
+	case address == 0xFF24:
+		apu.volume = value
+	case address == 0xFF25:
+		apu.panning = value
+	}
+}
+
+// Turning the APU off clears all its registers, but not wave RAM
+func (apu *Apu) powerOff() {
+	apu.square1 = Square{}
+	apu.square2 = Square{}
+	apu.wave = Wave{}
+	apu.noise = Noise{}
+	apu.volume = 0
+	apu.panning = 0
+	apu.registers = [0x20]byte{}
+}
@@ -0,0 +1,196 @@
+package gb
+
+import "testing"
+
+// Returns the output of channel after each period cycles
+func channelWaveform(mem *Memory, output func() byte, period int, count int) []byte {
+	var waveform []byte
+	for i := 0; i < count; i++ {
+		mem.apu.step(period, mem)
+		waveform = append(waveform, output())
+	}
+	return waveform
+}
+
+func checkWaveform(t *testing.T, name string, waveform []byte, expected []byte) {
+	for i := range expected {
+		if waveform[i] != expected[i] {
+			t.Errorf("%s waveform %v, expected %v", name, waveform, expected)
+			return
+		}
+	}
+}
+
+func TestSquareDuty(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	// 50% duty, volume 15, frequency 2047: one duty step every 4 cycles
+	mem.writeByte(0xff16, 0x80)
+	mem.writeByte(0xff17, 0xf0)
+	mem.writeByte(0xff18, 0xff)
+	mem.writeByte(0xff19, 0x87)
+	waveform := channelWaveform(&mem, mem.apu.square2.output, 4, 16)
+	checkWaveform(t, "50% square", waveform, []byte{0, 0, 0, 0, 15, 15, 15, 15, 0, 0, 0, 0, 15, 15, 15, 15})
+	// 12.5% duty, volume 8, frequency 2046: one duty step every 8 cycles
+	mem.writeByte(0xff16, 0x00)
+	mem.writeByte(0xff17, 0x80)
+	mem.writeByte(0xff18, 0xfe)
+	mem.writeByte(0xff19, 0x87)
+	waveform = channelWaveform(&mem, mem.apu.square2.output, 8, 8)
+	checkWaveform(t, "12.5% square", waveform, []byte{0, 0, 0, 0, 0, 0, 8, 0})
+}
+
+func TestSquareSweep(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	// sweep period 1, addition, shift 1
+	mem.writeByte(0xff10, 0x11)
+	mem.writeByte(0xff12, 0xf0)
+	mem.writeByte(0xff13, 0x00)
+	mem.writeByte(0xff14, 0x81)
+	for i := 0; i < 3; i++ {
+		mem.apu.clockSequencer()
+	}
+	if mem.apu.square1.frequency != 0x180 {
+		t.Errorf("0x%03x channel 1 frequency, expected 0x180", mem.apu.square1.frequency)
+	}
+	// 0x700 + 0x380 overflows and disables the channel on trigger
+	mem.writeByte(0xff14, 0x87)
+	if mem.apu.square1.enabled {
+		t.Errorf("channel 1 enabled after sweep overflow")
+	}
+}
+
+func TestLengthCounter(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	// length 62: the channel stops after 2 length clocks
+	mem.writeByte(0xff11, 0x3e)
+	mem.writeByte(0xff12, 0xf0)
+	mem.writeByte(0xff14, 0xc0)
+	if mem.readByte(0xff26) != 0xf1 {
+		t.Errorf("0x%02x in NR52, expected channel 1 on", mem.readByte(0xff26))
+	}
+	// the frame sequencer is clocked by DIV bit 4, length is clocked on even steps
+	for i := 0; i < 6; i++ {
+		mem.timer.step(4096, &mem)
+		mem.apu.step(0, &mem)
+	}
+	if mem.readByte(0xff26) != 0xf0 {
+		t.Errorf("0x%02x in NR52, expected channel 1 off", mem.readByte(0xff26))
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	// volume 15, decreasing every envelope clock
+	mem.writeByte(0xff21, 0xf1)
+	mem.writeByte(0xff23, 0x80)
+	for i := 0; i < 8*3; i++ {
+		mem.apu.clockSequencer()
+	}
+	if mem.apu.noise.envelope.volume != 12 {
+		t.Errorf("%d noise volume, expected 12", mem.apu.noise.envelope.volume)
+	}
+	// increasing envelope stops at 15
+	mem.writeByte(0xff21, 0xe9)
+	mem.writeByte(0xff23, 0x80)
+	for i := 0; i < 8*3; i++ {
+		mem.apu.clockSequencer()
+	}
+	if mem.apu.noise.envelope.volume != 15 {
+		t.Errorf("%d noise volume, expected 15", mem.apu.noise.envelope.volume)
+	}
+}
+
+func TestWaveChannel(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	for i := 0; i < 16; i++ {
+		mem.writeByte(0xff30+uint16(i), byte(i*2)<<4|byte(i*2+1)&0x0f)
+	}
+	// 100% level, frequency 2047: one sample every 2 cycles
+	mem.writeByte(0xff1a, 0x80)
+	mem.writeByte(0xff1c, 0x20)
+	mem.writeByte(0xff1d, 0xff)
+	mem.writeByte(0xff1e, 0x87)
+	waveform := channelWaveform(&mem, mem.apu.wave.output, 2, 32)
+	checkWaveform(t, "wave", waveform, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 0})
+	// 25% level
+	mem.writeByte(0xff1c, 0x60)
+	waveform = channelWaveform(&mem, mem.apu.wave.output, 2, 8)
+	checkWaveform(t, "wave at 25%", waveform, []byte{0, 0, 0, 1, 1, 1, 1, 2})
+}
+
+func TestNoiseChannel(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	// volume 15, divisor 8, shift 0: the LFSR is clocked every 8 cycles
+	mem.writeByte(0xff21, 0xf0)
+	mem.writeByte(0xff22, 0x00)
+	mem.writeByte(0xff23, 0x80)
+	waveform := channelWaveform(&mem, mem.apu.noise.output, 8, 32)
+	checkWaveform(t, "noise", waveform, []byte{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 15, 15,
+		15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 0, 15, 15, 15})
+	// the 7 bit LFSR repeats every 127 clocks
+	mem.writeByte(0xff22, 0x08)
+	mem.writeByte(0xff23, 0x80)
+	waveform = channelWaveform(&mem, mem.apu.noise.output, 8, 254)
+	checkWaveform(t, "7 bit noise", waveform[127:], waveform[:127])
+}
+
+func TestMixing(t *testing.T) {
+	var mem Memory
+	mem.apu.setSampleRate(44100)
+	mem.writeByte(0xff26, 0x80)
+	mem.writeByte(0xff24, 0x77)
+	// channel 2 on the left only
+	mem.writeByte(0xff25, 0x20)
+	mem.writeByte(0xff16, 0x80)
+	mem.writeByte(0xff17, 0xf0)
+	mem.writeByte(0xff18, 0x00)
+	mem.writeByte(0xff19, 0x86)
+	mem.apu.step(cpuFrequency/64, &mem)
+	samples := mem.apu.readSamples()
+	if len(samples) != 689*2 {
+		t.Fatalf("%d samples, expected %d", len(samples), 689*2)
+	}
+	var peak float32
+	for i := 0; i < len(samples); i += 2 {
+		if samples[i] > peak {
+			peak = samples[i]
+		}
+		if samples[i+1] != 0 {
+			t.Fatalf("%f right sample, expected silence", samples[i+1])
+		}
+	}
+	if peak < 0.1 || peak > 1 {
+		t.Errorf("%f left peak, expected a square wave", peak)
+	}
+	if len(mem.apu.readSamples()) != 0 {
+		t.Errorf("samples returned twice")
+	}
+}
+
+func TestApuPower(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff26, 0x80)
+	mem.writeByte(0xff30, 0x12)
+	mem.writeByte(0xff11, 0xbf)
+	if mem.readByte(0xff11) != 0xbf {
+		t.Errorf("0x%02x in NR11, expected 0xbf", mem.readByte(0xff11))
+	}
+	mem.writeByte(0xff26, 0x00)
+	mem.writeByte(0xff24, 0x77)
+	if mem.readByte(0xff11) != 0x3f || mem.readByte(0xff24) != 0x00 {
+		t.Errorf("registers not cleared while the APU is off")
+	}
+	if mem.readByte(0xff30) != 0x12 {
+		t.Errorf("wave RAM cleared when the APU is turned off")
+	}
+	if mem.readByte(0xff26) != 0x70 {
+		t.Errorf("0x%02x in NR52, expected 0x70", mem.readByte(0xff26))
+	}
+}
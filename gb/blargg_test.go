@@ -0,0 +1,97 @@
+package gb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Cycles a Blargg test ROM may run before printing its result, by ROM name.
+// ROMs not listed get blarggDefaultBudget.
+var blarggBudgets map[string]int = map[string]int{
+	"cpu_instrs":   60 * cpuFrequency,
+	"instr_timing": 5 * cpuFrequency,
+	"mem_timing":   5 * cpuFrequency,
+}
+
+const blarggDefaultBudget int = 30 * cpuFrequency
+
+// Run the Blargg test ROM rom until it prints Passed or Failed to the serial
+// port, or for budget cycles. Returns the serial output.
+func runBlargg(rom []byte, budget int) (string, error) {
+	emu, err := New(rom)
+	if err != nil {
+		return "", err
+	}
+	var output strings.Builder
+	for cycles := 0; cycles < budget; {
+		cycles += emu.StepInstruction()
+		if sent := emu.SerialOutput(); len(sent) > 0 {
+			output.Write(sent)
+			if strings.Contains(output.String(), "Passed") || strings.Contains(output.String(), "Failed") {
+				break
+			}
+		}
+	}
+	return output.String(), nil
+}
+
+// Run the Blargg test ROMs of testdata/blargg, which print their results to
+// the serial port. The test is skipped when the ROMs are absent.
+func TestBlargg(t *testing.T) {
+	roms, _ := filepath.Glob("testdata/blargg/*.gb")
+	if len(roms) == 0 {
+		t.Skip("no ROM in testdata/blargg")
+	}
+	if testing.Short() {
+		t.Skip("Blargg ROMs are slow to run")
+	}
+	for _, romPath := range roms {
+		romPath := romPath
+		name := strings.TrimSuffix(filepath.Base(romPath), ".gb")
+		t.Run(name, func(t *testing.T) {
+			rom, err := os.ReadFile(romPath)
+			if err != nil {
+				t.Fatalf("cannot read ROM: %s", err)
+			}
+			budget, ok := blarggBudgets[name]
+			if !ok {
+				budget = blarggDefaultBudget
+			}
+			output, err := runBlargg(rom, budget)
+			if err != nil {
+				t.Fatalf("cannot load ROM: %s", err)
+			}
+			if !strings.Contains(output, "Passed") {
+				t.Errorf("Passed not printed within %d cycles, output:\n%s", budget, output)
+			}
+		})
+	}
+}
+
+func TestRunBlargg(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	// send "Passed" with the internal clock, as the Blargg ROMs do:
+	// LD HL,0x0150; loop: LD A,(HL+); OR A; JR Z,done; LDH (0x01),A;
+	// LD A,0x81; LDH (0x02),A; wait: LDH A,(0x02); BIT 7,A; JR NZ,wait;
+	// JR loop; done: JR done
+	copy(rom[0x100:], []byte{0xc3, 0x60, 0x01})
+	copy(rom[0x150:], "Passed\x00")
+	copy(rom[0x160:], []byte{
+		0x21, 0x50, 0x01,
+		0x2a, 0xb7, 0x28, 0x0e, 0xe0, 0x01,
+		0x3e, 0x81, 0xe0, 0x02,
+		0xf0, 0x02, 0xcb, 0x7f, 0x20, 0xfa,
+		0x18, 0xee,
+		0x18, 0xfe,
+	})
+	rom[0x14D] = computeHeaderChecksum(rom)
+	output, err := runBlargg(rom, cpuFrequency)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if output != "Passed" {
+		t.Errorf("%q sent, expected %q", output, "Passed")
+	}
+}
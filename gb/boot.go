@@ -0,0 +1,148 @@
+package gb
+
+import "fmt"
+
+// Hardware models, with different boot ROMs and post-boot states
+type Model int
+
+const (
+	ModelDmg0 Model = iota
+	ModelDmg
+	ModelMgb
+	ModelSgb
+	ModelCgb
+)
+
+// Model names, as given on the command line
+var modelNames map[string]Model = map[string]Model{
+	"dmg0": ModelDmg0,
+	"dmg":  ModelDmg,
+	"mgb":  ModelMgb,
+	"sgb":  ModelSgb,
+	"cgb":  ModelCgb,
+}
+
+// Returns the model called name, false if there is none
+func ParseModel(name string) (Model, bool) {
+	model, ok := modelNames[name]
+	return model, ok
+}
+
+// Sizes of the DMG, MGB and SGB boot ROMs, and of the CGB boot ROM which is
+// also mapped at 0x0200-0x08FF
+const bootRomSize int = 0x100
+const cgbBootRomSize int = 0x900
+
+// CPU and IO registers left by the boot ROM when it jumps to 0x0100
+// see https://gbdev.io/pandocs/Power_Up_Sequence.html
+type PostBootState struct {
+	a, flags, b, c, d, e, h, l byte
+	// upper byte of the internal timer counter, it depends on the boot ROM duration
+	div byte
+	// registers differing from postBootIo
+	io map[uint16]byte
+}
+
+// IO registers after the DMG boot ROM
+var postBootIo map[uint16]byte = map[uint16]byte{
+	0xFF00: 0xCF, 0xFF01: 0x00, 0xFF02: 0x7E, 0xFF05: 0x00, 0xFF06: 0x00, 0xFF07: 0xF8,
+	0xFF0F: 0xE1, 0xFF10: 0x80, 0xFF11: 0xBF, 0xFF12: 0xF3, 0xFF13: 0xFF, 0xFF14: 0xBF,
+	0xFF16: 0x3F, 0xFF17: 0x00, 0xFF18: 0xFF, 0xFF19: 0xBF, 0xFF1A: 0x7F, 0xFF1B: 0xFF,
+	0xFF1C: 0x9F, 0xFF1D: 0xFF, 0xFF1E: 0xBF, 0xFF20: 0xFF, 0xFF21: 0x00, 0xFF22: 0x00,
+	0xFF23: 0xBF, 0xFF24: 0x77, 0xFF25: 0xF3, 0xFF26: 0xF1, 0xFF40: 0x91, 0xFF41: 0x85,
+	0xFF42: 0x00, 0xFF43: 0x00, 0xFF44: 0x00, 0xFF45: 0x00, 0xFF46: 0xFF, 0xFF47: 0xFC,
+	0xFF48: 0xFF, 0xFF49: 0xFF, 0xFF4A: 0x00, 0xFF4B: 0x00, 0xFFFF: 0x00,
+}
+
+var postBootStates map[Model]PostBootState = map[Model]PostBootState{
+	ModelDmg0: {a: 0x01, flags: 0x00, b: 0xFF, c: 0x13, d: 0x00, e: 0xC1, h: 0x84, l: 0x03, div: 0x18,
+		io: map[uint16]byte{0xFF41: 0x81, 0xFF44: 0x91}},
+	ModelDmg: {a: 0x01, flags: 0xB0, b: 0x00, c: 0x13, d: 0x00, e: 0xD8, h: 0x01, l: 0x4D, div: 0xAB},
+	ModelMgb: {a: 0xFF, flags: 0xB0, b: 0x00, c: 0x13, d: 0x00, e: 0xD8, h: 0x01, l: 0x4D, div: 0xAB},
+	ModelSgb: {a: 0x01, flags: 0x00, b: 0x00, c: 0x14, d: 0x00, e: 0x00, h: 0xC0, l: 0x60, div: 0xD8,
+		io: map[uint16]byte{0xFF00: 0xC7, 0xFF26: 0xF0}},
+	ModelCgb: {a: 0x11, flags: 0x80, b: 0x00, c: 0x00, d: 0xFF, e: 0x56, h: 0x00, l: 0x0D, div: 0x26,
+		io: map[uint16]byte{0xFF02: 0x7F, 0xFF46: 0x00, 0xFF4D: 0x7E, 0xFF4F: 0xFE, 0xFF51: 0xFF,
+			0xFF52: 0xFF, 0xFF53: 0xFF, 0xFF54: 0xFF, 0xFF55: 0xFF, 0xFF56: 0x3E, 0xFF70: 0xF8}},
+}
+
+// Set the CPU, memory and gpu in the state left by the boot ROM of model
+func postBoot(model Model, cpu *Register, mem *Memory, gpu *Gpu) {
+	state := postBootStates[model]
+	cpu.a = state.a
+	cpu.flags = state.flags
+	cpu.b = state.b
+	cpu.c = state.c
+	cpu.d = state.d
+	cpu.e = state.e
+	cpu.h = state.h
+	cpu.l = state.l
+	cpu.sp = 0xFFFE
+	cpu.pc = 0x0100
+	// the DMG and MGB boot ROMs only leave H and C set when the header checksum is not 0
+	if (model == ModelDmg || model == ModelMgb) && mem.cartridge != nil && mem.cartridge.headerChecksum == 0 {
+		cpu.flags = 0x80
+	}
+	mem.timer.counter = uint16(state.div) << 8
+
+	registers := make(map[uint16]byte)
+	for address, value := range postBootIo {
+		registers[address] = value
+	}
+	for address, value := range state.io {
+		registers[address] = value
+	}
+	// the APU must be on before its registers are written
+	mem.writeByte(0xFF26, 0x80)
+	for address, value := range registers {
+		switch {
+		case address == 0xFF14 || address == 0xFF19 || address == 0xFF1E || address == 0xFF23:
+			// write without the trigger bit, which would restart the channel
+			mem.writeByte(address, value&0x7F)
+		case address == 0xFF26 || address == 0xFF41 || address == 0xFF44:
+			// NR52 channel bits and the PPU state are set below
+		case address == 0xFF46:
+			// writing DMA would start a transfer
+			mem.io[address-0xFF00] = value
+		default:
+			mem.writeByte(address, value)
+		}
+	}
+	// the boot sound leaves channel 1 on, its envelope down to 0
+	if hasBit(uint16(registers[0xFF26]), 0) {
+		mem.apu.square1.enabled = true
+		mem.apu.square1.envelope.volume = 0
+	}
+
+	stat := registers[0xFF41]
+	gpu.mode = int(stat & 0x03)
+	gpu.line = int(registers[0xFF44])
+	// LY already reads 0 at the end of line 153, which is where the boot ROM
+	// leaves the PPU when STAT reports vblank and LY 0
+	if gpu.mode == 1 && gpu.line == 0 {
+		gpu.line = 153
+		gpu.mode_clock = 452
+	}
+	mem.io[0x41] = stat
+	mem.io[0x44] = registers[0xFF44]
+	// the boot ROM turned the LCD on, the PPU carries on where it left off
+	gpu.display = hasBit(uint16(registers[0xFF40]), 7)
+}
+
+// Map boot ROM data over the cartridge ROM, the CPU then starts at 0x0000
+func (mem *Memory) loadBootRom(data []byte) error {
+	if len(data) != bootRomSize && len(data) != cgbBootRomSize {
+		return fmt.Errorf("boot ROM is %d bytes, expected %d or %d", len(data), bootRomSize, cgbBootRomSize)
+	}
+	mem.bootRom = data
+	return nil
+}
+
+// Returns true when address reads from the boot ROM rather than the cartridge
+func (mem *Memory) bootRomMapped(address uint16) bool {
+	if mem.bootRom == nil {
+		return false
+	}
+	// the cartridge header stays visible at 0x0100-0x01FF with the CGB boot ROM
+	return int(address) < bootRomSize || (address >= 0x200 && int(address) < len(mem.bootRom))
+}
@@ -0,0 +1,91 @@
+package gb
+
+import "testing"
+
+func TestPostBoot(t *testing.T) {
+	var cpu Register
+	var mem Memory
+	var gpu Gpu
+	postBoot(ModelDmg, &cpu, &mem, &gpu)
+	if cpu.a != 0x01 || cpu.flags != 0xb0 || cpu.c != 0x13 || cpu.e != 0xd8 || cpu.getHLregister() != 0x014d {
+		t.Errorf("wrong CPU registers after the DMG boot ROM")
+	}
+	if cpu.sp != 0xfffe || cpu.pc != 0x0100 {
+		t.Errorf("0x%04x in SP and 0x%04x in PC, expected 0xfffe and 0x0100", cpu.sp, cpu.pc)
+	}
+	for address, expected := range map[uint16]byte{
+		0xff00: 0xcf, 0xff04: 0xab, 0xff07: 0xf8, 0xff0f: 0xe1, 0xff10: 0x80, 0xff11: 0xbf,
+		0xff12: 0xf3, 0xff14: 0xbf, 0xff24: 0x77, 0xff25: 0xf3, 0xff26: 0xf1, 0xff40: 0x91,
+		0xff41: 0x85, 0xff44: 0x00, 0xff47: 0xfc, 0xffff: 0x00,
+	} {
+		if value := mem.readByte(address); value != expected {
+			t.Errorf("0x%02x at 0x%04x, expected 0x%02x", value, address, expected)
+		}
+	}
+	// the PPU starts the first frame shortly after
+	stepGpuUntil(&gpu, &mem, 0, 2)
+	if gpu.line != 0 || gpu.mode != 2 {
+		t.Errorf("PPU at line %d mode %d, expected line 0 mode 2", gpu.line, gpu.mode)
+	}
+}
+
+func TestPostBootModels(t *testing.T) {
+	for model, expected := range map[Model]struct {
+		a    byte
+		nr52 byte
+		ly   byte
+	}{
+		ModelDmg0: {0x01, 0xf1, 0x91},
+		ModelMgb:  {0xff, 0xf1, 0x00},
+		ModelSgb:  {0x01, 0xf0, 0x00},
+		ModelCgb:  {0x11, 0xf1, 0x00},
+	} {
+		var cpu Register
+		var mem Memory
+		var gpu Gpu
+		postBoot(model, &cpu, &mem, &gpu)
+		if cpu.a != expected.a {
+			t.Errorf("model %d: 0x%02x in register A, expected 0x%02x", model, cpu.a, expected.a)
+		}
+		if mem.readByte(0xff26) != expected.nr52 || mem.readByte(0xff44) != expected.ly {
+			t.Errorf("model %d: wrong NR52 or LY", model)
+		}
+	}
+}
+
+func TestPostBootHeaderChecksum(t *testing.T) {
+	var cpu Register
+	var mem Memory
+	var gpu Gpu
+	mem.cartridge = &Cartridge{headerChecksum: 0}
+	postBoot(ModelDmg, &cpu, &mem, &gpu)
+	if cpu.flags != 0x80 {
+		t.Errorf("0x%02x in flags, expected 0x80 with a header checksum of 0", cpu.flags)
+	}
+}
+
+func TestBootRom(t *testing.T) {
+	var mem Memory
+	mem.rom[0x0000] = 0x11
+	mem.rom[0x0100] = 0x22
+	mem.bootRom = make([]byte, bootRomSize)
+	mem.bootRom[0x0000] = 0x31
+	if mem.readByte(0x0000) != 0x31 || mem.readByte(0x0100) != 0x22 {
+		t.Errorf("boot ROM not mapped over 0x0000-0x00FF")
+	}
+	mem.writeByte(0xff50, 0x00)
+	if mem.readByte(0x0000) != 0x31 {
+		t.Errorf("boot ROM unmapped by writing 0 to 0xFF50")
+	}
+	mem.writeByte(0xff50, 0x01)
+	if mem.readByte(0x0000) != 0x11 {
+		t.Errorf("boot ROM still mapped after writing 0xFF50")
+	}
+	// the CGB boot ROM leaves the cartridge header visible
+	mem.bootRom = make([]byte, cgbBootRomSize)
+	mem.bootRom[0x0200] = 0x33
+	mem.rom[0x0200] = 0x44
+	if mem.readByte(0x0100) != 0x22 || mem.readByte(0x0200) != 0x33 {
+		t.Errorf("CGB boot ROM not mapped over 0x0000-0x00FF and 0x0200-0x08FF")
+	}
+}
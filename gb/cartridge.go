@@ -0,0 +1,185 @@
+package gb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Memory bank controller of a cartridge, mapping the ROM (0x0000-0x7FFF)
+// and external RAM (0xA000-0xBFFF) address ranges
+type Mbc interface {
+	readByte(address uint16) byte
+	writeByte(address uint16, value byte)
+}
+
+// see https://gbdev.io/pandocs/The_Cartridge_Header.html
+type Cartridge struct {
+	title          string
+	cgbFlag        byte
+	sgbFlag        byte
+	cartridgeType  byte
+	romSize        int
+	ramSize        int
+	licensee       string
+	headerChecksum byte
+	globalChecksum uint16
+	rom            []byte
+	mbc            Mbc
+}
+
+var cartridgeTypes map[byte]string = map[byte]string{
+	0x00: "ROM ONLY",
+	0x01: "MBC1",
+	0x02: "MBC1+RAM",
+	0x03: "MBC1+RAM+BATTERY",
+	0x05: "MBC2",
+	0x06: "MBC2+BATTERY",
+	0x08: "ROM+RAM",
+	0x09: "ROM+RAM+BATTERY",
+	0x0B: "MMM01",
+	0x0C: "MMM01+RAM",
+	0x0D: "MMM01+RAM+BATTERY",
+	0x0F: "MBC3+TIMER+BATTERY",
+	0x10: "MBC3+TIMER+RAM+BATTERY",
+	0x11: "MBC3",
+	0x12: "MBC3+RAM",
+	0x13: "MBC3+RAM+BATTERY",
+	0x19: "MBC5",
+	0x1A: "MBC5+RAM",
+	0x1B: "MBC5+RAM+BATTERY",
+	0x1C: "MBC5+RUMBLE",
+	0x1D: "MBC5+RUMBLE+RAM",
+	0x1E: "MBC5+RUMBLE+RAM+BATTERY",
+	0x20: "MBC6",
+	0x22: "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	0xFC: "POCKET CAMERA",
+	0xFD: "BANDAI TAMA5",
+	0xFE: "HuC3",
+	0xFF: "HuC1+RAM+BATTERY",
+}
+
+// External RAM sizes in bytes, indexed by the RAM size code at 0x149
+var ramSizes [6]int = [6]int{0, 0x800, 0x2000, 0x8000, 0x20000, 0x10000}
+
+// Parse the cartridge header of rom and select its memory bank controller
+func newCartridge(rom []byte) (*Cartridge, error) {
+	if len(rom) < 0x150 {
+		return nil, fmt.Errorf("file is %d bytes long, too small to contain a cartridge header", len(rom))
+	}
+	var cart Cartridge
+	cart.rom = rom
+	cart.cgbFlag = rom[0x143]
+	cart.sgbFlag = rom[0x146]
+	cart.cartridgeType = rom[0x147]
+	cart.headerChecksum = rom[0x14D]
+	cart.globalChecksum = uint16(rom[0x14E])<<8 | uint16(rom[0x14F])
+
+	// on CGB cartridges the end of the title area holds the CGB flag
+	title := rom[0x134:0x144]
+	if cart.cgbFlag&0x80 != 0 {
+		title = rom[0x134:0x143]
+	}
+	cart.title = strings.TrimRight(string(title), "\x00")
+
+	if rom[0x14B] == 0x33 {
+		cart.licensee = string(rom[0x144:0x146])
+	} else {
+		cart.licensee = fmt.Sprintf("%02X", rom[0x14B])
+	}
+
+	if rom[0x148] > 8 {
+		return nil, fmt.Errorf("unknown ROM size code 0x%02X", rom[0x148])
+	}
+	cart.romSize = 0x8000 << rom[0x148]
+	if len(rom) < cart.romSize {
+		return nil, fmt.Errorf("file is %d bytes long, header declares a %d bytes ROM", len(rom), cart.romSize)
+	}
+	if int(rom[0x149]) >= len(ramSizes) {
+		return nil, fmt.Errorf("unknown RAM size code 0x%02X", rom[0x149])
+	}
+	cart.ramSize = ramSizes[rom[0x149]]
+
+	if checksum := computeHeaderChecksum(rom); checksum != cart.headerChecksum {
+		return nil, fmt.Errorf("header checksum mismatch: computed 0x%02X, header has 0x%02X", checksum, cart.headerChecksum)
+	}
+
+	switch cart.cartridgeType {
+	case 0x00, 0x08, 0x09:
+		cart.mbc = &RomOnly{rom: rom}
+	case 0x01, 0x02, 0x03:
+		cart.mbc = newMbc1(rom, cart.ramSize)
+	case 0x05, 0x06:
+		cart.mbc = newMbc2(rom)
+	case 0x0F, 0x10, 0x11, 0x12, 0x13:
+		hasRtc := cart.cartridgeType == 0x0F || cart.cartridgeType == 0x10
+		cart.mbc = newMbc3(rom, cart.ramSize, hasRtc)
+	case 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E:
+		rumble := cart.cartridgeType >= 0x1C
+		cart.mbc = newMbc5(rom, cart.ramSize, rumble)
+	default:
+		name, known := cartridgeTypes[cart.cartridgeType]
+		if !known {
+			return nil, fmt.Errorf("unknown cartridge type 0x%02X", cart.cartridgeType)
+		}
+		return nil, fmt.Errorf("unsupported cartridge type %s (0x%02X)", name, cart.cartridgeType)
+	}
+	return &cart, nil
+}
+
+// Cartridge without memory bank controller: 32 KiB of ROM and up to 8 KiB of RAM
+type RomOnly struct {
+	rom []byte
+	ram [0x2000]byte
+}
+
+func (mbc *RomOnly) readByte(address uint16) byte {
+	if address < 0x8000 {
+		return mbc.rom[address]
+	}
+	return mbc.ram[address-0xA000]
+}
+
+func (mbc *RomOnly) writeByte(address uint16, value byte) {
+	// writes to ROM are ignored
+	if address >= 0xA000 {
+		mbc.ram[address-0xA000] = value
+	}
+}
+
+// Checksum of header bytes 0x134-0x14C, verified by the boot ROM
+func computeHeaderChecksum(rom []byte) byte {
+	var checksum byte
+	for address := 0x134; address <= 0x14C; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	return checksum
+}
+
+// Sum of every ROM byte except the global checksum itself. The boot ROM does not check it.
+func computeGlobalChecksum(rom []byte) uint16 {
+	var checksum uint16
+	for address, value := range rom {
+		if address != 0x14E && address != 0x14F {
+			checksum += uint16(value)
+		}
+	}
+	return checksum
+}
+
+// Returns true if the global checksum matches the ROM content
+func (cart *Cartridge) validGlobalChecksum() bool {
+	return computeGlobalChecksum(cart.rom) == cart.globalChecksum
+}
+
+// Returns true if the cartridge RAM is battery backed
+func (cart *Cartridge) hasBattery() bool {
+	return strings.Contains(cart.typeName(), "BATTERY")
+}
+
+// Returns the name of the cartridge type
+func (cart *Cartridge) typeName() string {
+	if name, known := cartridgeTypes[cart.cartridgeType]; known {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%02X)", cart.cartridgeType)
+}
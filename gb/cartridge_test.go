@@ -0,0 +1,114 @@
+package gb
+
+import "testing"
+
+// Returns a blank ROM with a valid header for the given cartridge type, ROM and RAM size codes
+func makeRom(cartridgeType byte, romSize byte, ramSize byte) []byte {
+	rom := make([]byte, 0x8000<<romSize)
+	copy(rom[0x134:], "TESTROM")
+	rom[0x147] = cartridgeType
+	rom[0x148] = romSize
+	rom[0x149] = ramSize
+	rom[0x14D] = computeHeaderChecksum(rom)
+	return rom
+}
+
+func TestNewCartridge(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	rom[0x14B] = 0x01
+	rom[0x146] = 0x03
+	rom[0x14D] = computeHeaderChecksum(rom)
+	cart, err := newCartridge(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cart.title != "TESTROM" {
+		t.Errorf("%q title, expected %q", cart.title, "TESTROM")
+	}
+	if cart.licensee != "01" {
+		t.Errorf("%q licensee, expected %q", cart.licensee, "01")
+	}
+	if cart.sgbFlag != 0x03 {
+		t.Errorf("0x%02x SGB flag, expected 0x03", cart.sgbFlag)
+	}
+	if cart.romSize != 0x8000 || cart.ramSize != 0 {
+		t.Errorf("%d bytes of ROM and %d bytes of RAM, expected 32768 and 0", cart.romSize, cart.ramSize)
+	}
+	if _, ok := cart.mbc.(*RomOnly); !ok {
+		t.Errorf("%T memory bank controller, expected *RomOnly", cart.mbc)
+	}
+}
+
+func TestNewCartridgeCgbTitle(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	copy(rom[0x134:], "ABCDEFGHIJKLMNO")
+	rom[0x143] = 0xC0
+	rom[0x14B] = 0x33
+	copy(rom[0x144:], "01")
+	rom[0x14D] = computeHeaderChecksum(rom)
+	cart, err := newCartridge(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cart.title != "ABCDEFGHIJKLMNO" {
+		t.Errorf("%q title, expected %q", cart.title, "ABCDEFGHIJKLMNO")
+	}
+	if cart.licensee != "01" {
+		t.Errorf("%q new licensee, expected %q", cart.licensee, "01")
+	}
+}
+
+func TestNewCartridgeErrors(t *testing.T) {
+	short := makeRom(0x00, 0, 0)[:0x100]
+	badChecksum := makeRom(0x00, 0, 0)
+	badChecksum[0x14D]++
+	truncated := makeRom(0x00, 2, 0)[:0x8000]
+	badRomSize := makeRom(0x00, 0, 0)
+	badRomSize[0x148] = 0x52
+	badRomSize[0x14D] = computeHeaderChecksum(badRomSize)
+	badType := makeRom(0x42, 0, 0)
+	for name, rom := range map[string][]byte{
+		"short file":      short,
+		"header checksum": badChecksum,
+		"truncated file":  truncated,
+		"ROM size code":   badRomSize,
+		"cartridge type":  badType,
+	} {
+		if _, err := newCartridge(rom); err == nil {
+			t.Errorf("no error for invalid %s", name)
+		}
+	}
+}
+
+func TestGlobalChecksum(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	checksum := computeGlobalChecksum(rom)
+	rom[0x14E] = byte(checksum >> 8)
+	rom[0x14F] = byte(checksum)
+	cart, err := newCartridge(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !cart.validGlobalChecksum() {
+		t.Errorf("global checksum 0x%04x not valid", cart.globalChecksum)
+	}
+}
+
+func TestRomOnly(t *testing.T) {
+	var mem Memory
+	rom := makeRom(0x08, 0, 2)
+	rom[0x1234] = 0x56
+	cart, err := newCartridge(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mem.loadCartridge(cart)
+	mem.writeByte(0x1234, 0)
+	if mem.readByte(0x1234) != 0x56 {
+		t.Errorf("write to ROM changed its content")
+	}
+	mem.writeByte(0xA010, 0x78)
+	if mem.readByte(0xA010) != 0x78 {
+		t.Errorf("%d in external RAM, expected %d", mem.readByte(0xA010), 0x78)
+	}
+}
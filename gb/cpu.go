@@ -1,4 +1,4 @@
-package main
+package gb
 
 type Register struct {
 	a     byte
@@ -12,25 +12,36 @@ type Register struct {
 	sp    uint16
 	pc    uint16
 	clock int
-}
-
+	// interrupt master enable, and the number of instructions left before EI takes effect
+	ime      bool
+	imeDelay int
+	// low power states entered by HALT and STOP
+	halted  bool
+	stopped bool
+	// set when HALT is executed with IME reset and an interrupt already pending:
+	// the byte following HALT is read twice
+	haltBug bool
+}
+
+// Cycles of each opcode, conditional jumps, calls and returns not taken.
+// CB prefixed opcodes use tickscb.
 var ticks [256]int = [256]int{
-	2, 6, 4, 4, 2, 2, 4, 4, 10, 4, 4, 4, 2, 2, 4, 4,
-	2, 6, 4, 4, 2, 2, 4, 4, 4, 4, 4, 4, 2, 2, 4, 4,
-	0, 6, 4, 4, 2, 2, 4, 2, 0, 4, 4, 4, 2, 2, 4, 2,
-	4, 6, 4, 4, 6, 6, 6, 2, 0, 4, 4, 4, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	4, 4, 4, 4, 4, 4, 2, 4, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2,
-	0, 6, 0, 6, 0, 8, 4, 8, 0, 2, 0, 0, 0, 6, 4, 8,
-	0, 6, 0, 0, 0, 8, 4, 8, 0, 8, 0, 0, 0, 0, 4, 8,
-	6, 6, 4, 0, 0, 8, 4, 8, 8, 2, 8, 0, 0, 0, 4, 8,
-	6, 6, 4, 2, 0, 8, 4, 8, 6, 4, 8, 2, 0, 0, 4, 8}
+	4, 12, 8, 8, 4, 4, 8, 4, 20, 8, 8, 8, 4, 4, 8, 4,
+	4, 12, 8, 8, 4, 4, 8, 4, 12, 8, 8, 8, 4, 4, 8, 4,
+	8, 12, 8, 8, 4, 4, 8, 4, 8, 8, 8, 8, 4, 4, 8, 4,
+	8, 12, 8, 8, 12, 12, 12, 4, 8, 8, 8, 8, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	8, 8, 8, 8, 8, 8, 4, 8, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4,
+	8, 12, 12, 16, 12, 16, 8, 16, 8, 16, 12, 4, 12, 24, 8, 16,
+	8, 12, 12, 0, 12, 16, 8, 16, 8, 16, 12, 0, 12, 0, 8, 16,
+	12, 12, 8, 0, 0, 16, 8, 16, 16, 4, 16, 0, 0, 0, 8, 16,
+	12, 12, 8, 4, 0, 16, 8, 16, 12, 8, 16, 4, 0, 0, 8, 16}
 
 var tickscb [256]int = [256]int{
 	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
@@ -41,14 +52,14 @@ var tickscb [256]int = [256]int{
 	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
 	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
 	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8,
-	8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8}
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8,
+	8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8}
 
 /* *************************************** */
 /* Helper functions                        */
@@ -79,6 +90,51 @@ func (reg *Register) setHLregisters(value uint16) {
 	reg.l = a
 }
 
+// Returns the value of register source. For HL, read memory at address HL instead.
+func (reg *Register) readRegister(source string, mem *Memory) byte {
+	switch source {
+	case "A":
+		return reg.a
+	case "B":
+		return reg.b
+	case "C":
+		return reg.c
+	case "D":
+		return reg.d
+	case "E":
+		return reg.e
+	case "H":
+		return reg.h
+	case "L":
+		return reg.l
+	case "HL":
+		return mem.readByte(reg.getHLregister())
+	}
+	return 0
+}
+
+// Sets register destination to value. For HL, write memory at address HL instead.
+func (reg *Register) writeRegister(destination string, value byte, mem *Memory) {
+	switch destination {
+	case "A":
+		reg.a = value
+	case "B":
+		reg.b = value
+	case "C":
+		reg.c = value
+	case "D":
+		reg.d = value
+	case "E":
+		reg.e = value
+	case "H":
+		reg.h = value
+	case "L":
+		reg.l = value
+	case "HL":
+		mem.writeByte(reg.getHLregister(), value)
+	}
+}
+
 /* *************************************** */
 /* Flags setting function                  */
 /* *************************************** */
@@ -164,7 +220,7 @@ func (reg *Register) ldAn(source string, mem *Memory) {
 	reg.a = mem.readByte(reg_map[source])
 }
 
-// Loads value od register source in register A
+// Load register A at the address held in register source, or in the pair of registers source
 func (reg *Register) ldnA(source string, mem *Memory) {
 	reg_map := map[string]byte{
 		"A": reg.a,
@@ -176,7 +232,7 @@ func (reg *Register) ldnA(source string, mem *Memory) {
 		"L": reg.l,
 	}
 	if len(source) == 1 {
-		reg.a = reg_map[source]
+		mem.writeByte(uint16(reg_map[source]), reg.a)
 	} else {
 		switch source {
 		case "BC":
@@ -193,12 +249,12 @@ func (reg *Register) ldnA(source string, mem *Memory) {
 
 // Load value in the io memory bank at address in register C on register A
 func (reg *Register) ldAC(mem *Memory) {
-	reg.a = mem.io[reg.c]
+	reg.a = mem.readByte(0xFF00 + uint16(reg.c))
 }
 
 // Load value in register A in io memory bank at address in register C
 func (reg *Register) ldCA(mem *Memory) {
-	mem.io[reg.c] = reg.a
+	mem.writeByte(0xFF00+uint16(reg.c), reg.a)
 }
 
 // Load value at address HL in register A and decrement HL
@@ -235,22 +291,12 @@ func (reg *Register) ldiHLA(mem *Memory) {
 
 // Load value in register A in io memory bank at address value
 func (reg *Register) ldhnA(value byte, mem *Memory) {
-	if value == 0 {
-		if reg.a == 0x20 {
-			mem.io[0] = 0xef
-		} else if reg.a == 0x10 {
-			mem.io[0] = 0xdf
-		} else {
-			mem.io[value] = reg.a
-		}
-	} else {
-		mem.io[value] = reg.a
-	}
+	mem.writeByte(0xFF00+uint16(value), reg.a)
 }
 
 // Load value in io memory bank at address value in register A
 func (reg *Register) ldhAn(value byte, mem *Memory) {
-	reg.a = mem.io[value]
+	reg.a = mem.readByte(0xFF00 + uint16(value))
 }
 
 /* *************************************** */
@@ -281,26 +327,23 @@ func (reg *Register) ldSPHL() {
 	reg.sp = value
 }
 
-// Load SP+value into HL
-func (reg *Register) ldHLSPn(value byte) {
-	result := uint16(value) + reg.sp
-	reg.setHLregisters(uint16(value))
+// Returns SP plus the signed offset value, and sets the flags of the addition:
+// H and C come from the unsigned addition of the low bytes
+func (reg *Register) offsetSP(value byte) uint16 {
 	// reset Z flag
 	reg.setRegisterFlag(false, 7)
 	// reset N flag
 	reg.setRegisterFlag(false, 6)
 	// set H flag
-	if (reg.sp&0x000f + uint16(value&0x0f)) > 0x0F {
-		reg.setRegisterFlag(true, 5)
-	} else {
-		reg.setRegisterFlag(false, 5)
-	}
-	//set C flag
-	if (result & 0xff00) != 0 {
-		reg.setRegisterFlag(true, 4)
-	} else {
-		reg.setRegisterFlag(false, 4)
-	}
+	reg.setRegisterFlag(reg.sp&0x0f+uint16(value&0x0f) > 0x0f, 5)
+	// set C flag
+	reg.setRegisterFlag(reg.sp&0xff+uint16(value) > 0xff, 4)
+	return reg.sp + uint16(int8(value))
+}
+
+// Load SP+value into HL, value being signed
+func (reg *Register) ldHLSPn(value byte) {
+	reg.setHLregisters(reg.offsetSP(value))
 }
 
 // Load SP at value address
@@ -308,7 +351,7 @@ func (reg *Register) ldnnSP(value uint16, mem *Memory) {
 	mem.writeWord(value, reg.sp)
 }
 
-// Push pair of registers on top of stack and dercrement stack twice
+// Decrement SP twice and push pair of registers on top of stack
 func (reg *Register) pushnn(registers string, mem *Memory) {
 	var value uint16
 	switch registers {
@@ -324,15 +367,15 @@ func (reg *Register) pushnn(registers string, mem *Memory) {
 		value = reg.getHLregister()
 
 	}
+	reg.sp -= 2
 	mem.writeWord(reg.sp, value)
-	reg.sp = reg.sp - 2
 }
 
 // Pop 16 bits on top of the stack and put in pair of registers and increment SP twice
 func (reg *Register) popnn(registers string, mem *Memory) {
-	reg.sp += 2
 	r2 := mem.readByte(reg.sp)
 	r1 := mem.readByte(reg.sp + 1)
+	reg.sp += 2
 	switch registers {
 	case "AF":
 		reg.a = r1
@@ -580,26 +623,9 @@ func (reg *Register) addHLn(value uint16) {
 	reg.setHLregisters(uint16(result))
 }
 
-// Add value to register SP
-func (reg *Register) addSPn(value uint16) {
-	result := uint32(reg.sp) + uint32(value)
-	// zero flag
-	reg.setRegisterFlag(false, 7)
-	// negative flag
-	reg.setRegisterFlag(false, 6)
-	// carry flag
-	if (result & 0xFF0000) != 0 {
-		reg.setRegisterFlag(true, 4)
-	} else {
-		reg.setRegisterFlag(false, 4)
-	}
-	// half carry flag
-	if ((uint16(result) & 0x0F) + (value & 0x0F)) > 0x0F {
-		reg.setRegisterFlag(true, 5)
-	} else {
-		reg.setRegisterFlag(false, 5)
-	}
-	reg.sp = uint16(result)
+// Add signed value to register SP
+func (reg *Register) addSPn(value byte) {
+	reg.sp = reg.offsetSP(value)
 }
 
 // Increment register
@@ -641,50 +667,12 @@ func (reg *Register) decnn(register string) {
 /* *************************************** */
 
 // Swap upper and lower nibbles of register
-func (reg *Register) swapn(register string) {
-	var value byte
-	switch register {
-	case "A":
-		value := reg.a
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.a = value
-	case "B":
-		value := reg.b
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.b = value
-	case "C":
-		value := reg.c
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.c = value
-	case "D":
-		value := reg.d
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.d = value
-	case "E":
-		value := reg.e
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.e = value
-	case "H":
-		value := reg.h
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.h = value
-	case "L":
-		value := reg.l
-		value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
-		reg.l = value
-	case "HL":
-		h := reg.h
-		l := reg.l
-		reg.h = l
-		reg.l = h
-		if concatenateBytes(reg.l, reg.h) != 0 {
-			value = 1
-		}
-	}
+func (reg *Register) swapn(register string, mem *Memory) {
+	value := reg.readRegister(register, mem)
+	value = ((value & 0x0f) << 4) | ((value & 0xf0) >> 4)
+	reg.writeRegister(register, value, mem)
 	// zero flag
-	if value == 0 {
-		reg.setRegisterFlag(true, 7)
-	}
+	reg.setRegisterFlag(value == 0, 7)
 	// negative flag
 	reg.setRegisterFlag(false, 6)
 	// half carry flag
@@ -749,6 +737,29 @@ func (reg *Register) ccf() {
 	reg.setRegisterFlag(false, 5)
 }
 
+// Halt the CPU until an enabled interrupt is pending
+func (reg *Register) halt(mem *Memory) {
+	if !reg.ime && mem.pendingInterrupts() != 0 {
+		// HALT bug: the CPU does not halt and fails to increment PC after the next fetch
+		reg.haltBug = true
+	} else {
+		reg.halted = true
+	}
+}
+
+// Stop the CPU until a button is pressed. In CGB mode, if a speed switch
+// was prepared in KEY1 (0xFF4D), switch speed instead.
+func (reg *Register) stop(mem *Memory) {
+	if mem.cgb && hasBit(uint16(mem.readByte(0xff4d)), 0) {
+		mem.doubleSpeed = !mem.doubleSpeed
+		mem.writeByte(0xff4d, 0x00)
+		return
+	}
+	reg.stopped = true
+	// entering STOP resets DIV
+	mem.timer.resetDiv()
+}
+
 // Set carry flag
 func (reg *Register) scf() {
 	// carry flag
@@ -858,26 +869,10 @@ func (reg *Register) rrcA() {
 	reg.a = byte(value)
 }
 
-// Rotate register destination left
-func (reg *Register) rln(destination string) {
-	var register byte
-	switch destination {
-	case "A":
-		register = reg.a
-	case "B":
-		register = reg.b
-	case "C":
-		register = reg.c
-	case "D":
-		register = reg.d
-	case "E":
-		register = reg.e
-	case "H":
-		register = reg.h
-	case "L":
-		register = reg.l
-	}
-	value := uint16(register) << 1
+// Rotate register destination left through carry
+func (reg *Register) rln(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
+	value := register << 1
 	if hasBit(uint16(reg.flags), 4) {
 		value++
 	}
@@ -897,44 +892,13 @@ func (reg *Register) rln(destination string) {
 	} else {
 		reg.setRegisterFlag(false, 4)
 	}
-	switch destination {
-	case "A":
-		reg.a = byte(value)
-	case "B":
-		reg.b = byte(value)
-	case "C":
-		reg.c = byte(value)
-	case "D":
-		reg.d = byte(value)
-	case "E":
-		reg.e = byte(value)
-	case "H":
-		reg.h = byte(value)
-	case "L":
-		reg.l = byte(value)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
-// Rotate register destination left thorugh carry
-func (reg *Register) rlcn(destination string) {
-	var register byte
-	switch destination {
-	case "A":
-		register = reg.a
-	case "B":
-		register = reg.b
-	case "C":
-		register = reg.c
-	case "D":
-		register = reg.d
-	case "E":
-		register = reg.e
-	case "H":
-		register = reg.h
-	case "L":
-		register = reg.l
-	}
-	value := uint16(register) << 1
+// Rotate register destination left, old bit 7 to carry flag
+func (reg *Register) rlcn(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
+	value := (register << 1) | (register >> 7)
 	//zero flag
 	if value != 0 {
 		reg.setRegisterFlag(false, 7)
@@ -951,44 +915,13 @@ func (reg *Register) rlcn(destination string) {
 	} else {
 		reg.setRegisterFlag(false, 4)
 	}
-	switch destination {
-	case "A":
-		reg.a = byte(value)
-	case "B":
-		reg.b = byte(value)
-	case "C":
-		reg.c = byte(value)
-	case "D":
-		reg.d = byte(value)
-	case "E":
-		reg.e = byte(value)
-	case "H":
-		reg.h = byte(value)
-	case "L":
-		reg.l = byte(value)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
-// Rotate register destination right through carry
-func (reg *Register) rrcn(destination string) {
-	var register byte
-	switch destination {
-	case "A":
-		register = reg.a
-	case "B":
-		register = reg.b
-	case "C":
-		register = reg.c
-	case "D":
-		register = reg.d
-	case "E":
-		register = reg.e
-	case "H":
-		register = reg.h
-	case "L":
-		register = reg.l
-	}
-	value := uint16(register) >> 1
+// Rotate register destination right, old bit 0 to carry flag
+func (reg *Register) rrcn(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
+	value := (register >> 1) | (register << 7)
 	//zero flag
 	if value != 0 {
 		reg.setRegisterFlag(false, 7)
@@ -1005,47 +938,15 @@ func (reg *Register) rrcn(destination string) {
 	} else {
 		reg.setRegisterFlag(false, 4)
 	}
-	switch destination {
-	case "A":
-		reg.a = byte(value)
-	case "B":
-		reg.b = byte(value)
-	case "C":
-		reg.c = byte(value)
-	case "D":
-		reg.d = byte(value)
-	case "E":
-		reg.e = byte(value)
-	case "H":
-		reg.h = byte(value)
-	case "L":
-		reg.l = byte(value)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
-// Rotate register destination right.
-func (reg *Register) rrn(destination string) {
-	var register byte
-	switch destination {
-	case "A":
-		register = reg.a
-	case "B":
-		register = reg.b
-	case "C":
-		register = reg.c
-	case "D":
-		register = reg.d
-	case "E":
-		register = reg.e
-	case "H":
-		register = reg.h
-	case "L":
-		register = reg.l
-	}
-	value := uint16(register) >> 1
+// Rotate register destination right through carry
+func (reg *Register) rrn(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
+	value := register >> 1
 	if hasBit(uint16(reg.flags), 4) {
-		var pos uint16 = 7
-		value |= 1 << pos
+		value |= 1 << 7
 	}
 	//zero flag
 	if value != 0 {
@@ -1063,43 +964,14 @@ func (reg *Register) rrn(destination string) {
 	} else {
 		reg.setRegisterFlag(false, 4)
 	}
-	switch destination {
-	case "A":
-		reg.a = byte(value)
-	case "B":
-		reg.b = byte(value)
-	case "C":
-		reg.c = byte(value)
-	case "D":
-		reg.d = byte(value)
-	case "E":
-		reg.e = byte(value)
-	case "H":
-		reg.h = byte(value)
-	case "L":
-		reg.l = byte(value)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
 // Shift register destination left
-func (reg *Register) slan(destination string) {
-	reg_map := map[string]*byte{
-		"A": &(reg.a),
-		"B": &(reg.b),
-		"C": &(reg.c),
-		"D": &(reg.d),
-		"E": &(reg.e),
-		"H": &(reg.h),
-		"L": &(reg.l),
-	}
-	var register uint16
-	if destination == "HL" {
-		register = reg.getHLregister()
-	} else {
-		register = uint16(*reg_map[destination])
-	}
+func (reg *Register) slan(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
 	//carry flag
-	if hasBit(register, 0) {
+	if hasBit(uint16(register), 7) {
 		reg.setRegisterFlag(true, 4)
 	} else {
 		reg.setRegisterFlag(false, 4)
@@ -1115,43 +987,19 @@ func (reg *Register) slan(destination string) {
 	reg.setRegisterFlag(false, 6)
 	// half carry flag
 	reg.setRegisterFlag(false, 5)
-	if destination == "HL" {
-		reg.setHLregisters(register)
-	} else {
-		*reg_map[destination] = byte(register)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
-// Shift register destination right
-func (reg *Register) sran(destination string) {
-	reg_map := map[string]*byte{
-		"A": &(reg.a),
-		"B": &(reg.b),
-		"C": &(reg.c),
-		"D": &(reg.d),
-		"E": &(reg.e),
-		"H": &(reg.h),
-		"L": &(reg.l),
-	}
-	var register uint16
-	if destination == "HL" {
-		register = reg.getHLregister()
-	} else {
-		register = uint16(*reg_map[destination])
-	}
-	msb := hasBit(register, 7)
+// Shift register destination right, keeping bit 7
+func (reg *Register) sran(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
 	//carry flag
-	if hasBit(register, 7) {
+	if hasBit(uint16(register), 0) {
 		reg.setRegisterFlag(true, 4)
 	} else {
 		reg.setRegisterFlag(false, 4)
 	}
-	value := register >> 1
-	if msb {
-		register |= (1 << 7)
-	} else {
-		register &^= (1 << 7)
-	}
+	value := (register >> 1) | (register & 0x80)
 	//zero flag
 	if value != 0 {
 		reg.setRegisterFlag(false, 7)
@@ -1162,32 +1010,14 @@ func (reg *Register) sran(destination string) {
 	reg.setRegisterFlag(false, 6)
 	// half carry flag
 	reg.setRegisterFlag(false, 5)
-	if destination == "HL" {
-		reg.setHLregisters(register)
-	} else {
-		*reg_map[destination] = byte(register)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
 // Shift register destination right
-func (reg *Register) srln(destination string) {
-	reg_map := map[string]*byte{
-		"A": &(reg.a),
-		"B": &(reg.b),
-		"C": &(reg.c),
-		"D": &(reg.d),
-		"E": &(reg.e),
-		"H": &(reg.h),
-		"L": &(reg.l),
-	}
-	var register uint16
-	if destination == "HL" {
-		register = reg.getHLregister()
-	} else {
-		register = uint16(*reg_map[destination])
-	}
+func (reg *Register) srln(destination string, mem *Memory) {
+	register := reg.readRegister(destination, mem)
 	//carry flag
-	if hasBit(register, 7) {
+	if hasBit(uint16(register), 0) {
 		reg.setRegisterFlag(true, 4)
 	} else {
 		reg.setRegisterFlag(false, 4)
@@ -1203,38 +1033,18 @@ func (reg *Register) srln(destination string) {
 	reg.setRegisterFlag(false, 6)
 	// half carry flag
 	reg.setRegisterFlag(false, 5)
-	if destination == "HL" {
-		reg.setHLregisters(register)
-	} else {
-		*reg_map[destination] = byte(register)
-	}
+	reg.writeRegister(destination, value, mem)
 }
 
 /* *************************************** */
 /* Bit opcodes                             */
 /* *************************************** */
 
-// Set zero flag to register destination bit at position pos
-func (reg *Register) bitBr(destination string, pos uint16) {
-	var test bool
-	switch destination {
-	case "A":
-		test = hasBit(uint16(reg.a), pos)
-	case "B":
-		test = hasBit(uint16(reg.b), pos)
-	case "C":
-		test = hasBit(uint16(reg.c), pos)
-	case "D":
-		test = hasBit(uint16(reg.d), pos)
-	case "E":
-		test = hasBit(uint16(reg.e), pos)
-	case "H":
-		test = hasBit(uint16(reg.h), pos)
-	case "L":
-		test = hasBit(uint16(reg.l), pos)
-	}
+// Set zero flag if bit at position pos of register destination is reset
+func (reg *Register) bitBr(destination string, pos uint16, mem *Memory) {
+	test := hasBit(uint16(reg.readRegister(destination, mem)), pos)
 	// zero flag
-	reg.setRegisterFlag(test, 7)
+	reg.setRegisterFlag(!test, 7)
 	// negative flag
 	reg.setRegisterFlag(false, 6)
 	// half carry flag
@@ -1242,45 +1052,17 @@ func (reg *Register) bitBr(destination string, pos uint16) {
 }
 
 // Set bit at position pos in register destination
-func (reg *Register) setBr(destination string, pos uint16) {
+func (reg *Register) setBr(destination string, pos uint16, mem *Memory) {
 	var mask byte = 1
-	switch destination {
-	case "A":
-		reg.a |= (mask << pos)
-	case "B":
-		reg.b |= (mask << pos)
-	case "C":
-		reg.c |= (mask << pos)
-	case "D":
-		reg.d |= (mask << pos)
-	case "E":
-		reg.e |= (mask << pos)
-	case "H":
-		reg.h |= (mask << pos)
-	case "L":
-		reg.l |= (mask << pos)
-	}
+	value := reg.readRegister(destination, mem)
+	reg.writeRegister(destination, value|(mask<<pos), mem)
 }
 
 // reset bit at position pos in register destination
-func (reg *Register) resBr(destination string, pos uint16) {
+func (reg *Register) resBr(destination string, pos uint16, mem *Memory) {
 	var mask byte = 1
-	switch destination {
-	case "A":
-		reg.a &^= (mask << pos)
-	case "B":
-		reg.b &^= (mask << pos)
-	case "C":
-		reg.c &^= (mask << pos)
-	case "D":
-		reg.d &^= (mask << pos)
-	case "E":
-		reg.e &^= (mask << pos)
-	case "H":
-		reg.h &^= (mask << pos)
-	case "L":
-		reg.l &^= (mask << pos)
-	}
+	value := reg.readRegister(destination, mem)
+	reg.writeRegister(destination, value&^(mask<<pos), mem)
 }
 
 /* *************************************** */
@@ -1375,8 +1157,8 @@ func (reg *Register) jrccn(n byte, condition string) {
 // Push address of next instruction on top of stack and jump to destination
 func (reg *Register) callnn(destination uint16, mem *Memory) {
 	reg.pc += 2
-	mem.writeWord(reg.sp, reg.pc)
 	reg.sp -= 2
+	mem.writeWord(reg.sp, reg.pc)
 	reg.pc = destination
 }
 
@@ -1414,8 +1196,8 @@ func (reg *Register) callccnn(n uint16, condition string, mem *Memory) {
 
 // Pop value from stack and jump
 func (reg *Register) ret(mem *Memory) {
-	reg.sp += 2
 	address := mem.readWord(reg.sp)
+	reg.sp += 2
 	reg.pc = address
 }
 
@@ -1438,9 +1220,36 @@ func (reg *Register) retcc(mem *Memory, condition string) {
 	}
 }
 
+// Execute the next instruction, or idle for one cycle while halted or stopped,
+// then service pending interrupts
+func (reg *Register) step(mem *Memory) {
+	if reg.stopped {
+		// time still passes for the PPU, a selected joypad line going low wakes the CPU up
+		reg.clock = 4
+		if mem.joypad.readByte()&0x0F != 0x0F || hasBit(uint16(mem.readByte(0xff0f)), uint16(joypadInterrupt)) {
+			reg.stopped = false
+		}
+		return
+	}
+	if reg.halted {
+		// peripherals keep running while halted
+		reg.clock = 4
+		if mem.pendingInterrupts() != 0 {
+			reg.halted = false
+		}
+	} else {
+		reg.execute(mem.readByte(reg.pc), mem)
+	}
+	reg.handleInterrupts(mem)
+}
+
 // Execute opcode
 func (reg *Register) execute(opcode byte, mem *Memory) {
-	reg.pc++
+	if reg.haltBug {
+		reg.haltBug = false
+	} else {
+		reg.pc++
+	}
 	reg.clock = ticks[opcode]
 	switch opcode {
 	case 0x00:
@@ -1485,7 +1294,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0x0f:
 		reg.rrcA()
 	case 0x10:
-		//stop
+		reg.stop(mem)
 		reg.pc++
 	case 0x11:
 		value := mem.readWord(reg.pc)
@@ -1596,8 +1405,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 		reg.jrccn(value, "C")
 		reg.pc++
 	case 0x39:
-		value := mem.readWord(reg.sp)
-		reg.addHLn(value)
+		reg.addHLn(reg.sp)
 	case 0x3a:
 		reg.ldiAHL(mem)
 	case 0x3b:
@@ -1720,7 +1528,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0x75:
 		reg.ldr1r2("HL", "L", mem)
 	case 0x76:
-		// halt
+		reg.halt(mem)
 	case 0x77:
 		reg.ldr1r2("HL", "A", mem)
 	case 0x78:
@@ -1961,7 +1769,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 		reg.jpccnn(value, "Z")
 	case 0xcb:
 		opcode = mem.readByte(reg.pc)
-		reg.executeCb(opcode)
+		reg.executeCb(opcode, mem)
 	case 0xcc:
 		value := mem.readWord(reg.pc)
 		reg.callccnn(value, "Z", mem)
@@ -1998,7 +1806,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0xd8:
 		reg.retcc(mem, "C")
 	case 0xd9:
-		//return and interrupt (not implemented)
+		reg.reti(mem)
 	case 0xda:
 		value := mem.readWord(reg.pc)
 		reg.jpccnn(value, "C")
@@ -2036,7 +1844,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0xe7:
 		reg.rst(0x20, mem)
 	case 0xe8:
-		value := mem.readWord(reg.pc)
+		value := mem.readByte(reg.pc)
 		reg.addSPn(value)
 		reg.pc++
 	case 0xe9:
@@ -2063,10 +1871,9 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0xf1:
 		reg.popnn("AF", mem)
 	case 0xf2:
-		reg.ldAn("C", mem)
-		reg.pc++
+		reg.ldAC(mem)
 	case 0xf3:
-		//not implemented
+		reg.di()
 	case 0xf4:
 		//not used
 	case 0xf5:
@@ -2088,7 +1895,7 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 		reg.a = mem.readByte(address)
 		reg.pc += 2
 	case 0xfb:
-		//not implemented
+		reg.ei()
 	case 0xfc:
 		//not used
 	case 0xfd:
@@ -2100,523 +1907,525 @@ func (reg *Register) execute(opcode byte, mem *Memory) {
 	case 0xff:
 		reg.rst(0x38, mem)
 	}
+	reg.updateIme()
 }
 
-func (reg *Register) executeCb(opcode byte) {
+// Execute CB prefixed opcode
+func (reg *Register) executeCb(opcode byte, mem *Memory) {
 	reg.pc++
 	reg.clock = tickscb[opcode]
 	switch opcode {
 	case 0x00:
-		reg.rlcn("B")
+		reg.rlcn("B", mem)
 	case 0x01:
-		reg.rlcn("C")
+		reg.rlcn("C", mem)
 	case 0x02:
-		reg.rlcn("D")
+		reg.rlcn("D", mem)
 	case 0x03:
-		reg.rlcn("E")
+		reg.rlcn("E", mem)
 	case 0x04:
-		reg.rlcn("H")
+		reg.rlcn("H", mem)
 	case 0x05:
-		reg.rlcn("L")
+		reg.rlcn("L", mem)
 	case 0x06:
-		reg.rlcn("HL")
+		reg.rlcn("HL", mem)
 	case 0x07:
-		reg.rlcA()
+		reg.rlcn("A", mem)
 	case 0x08:
-		reg.rrcn("B")
+		reg.rrcn("B", mem)
 	case 0x09:
-		reg.rrcn("C")
+		reg.rrcn("C", mem)
 	case 0x0a:
-		reg.rrcn("D")
+		reg.rrcn("D", mem)
 	case 0x0b:
-		reg.rrcn("E")
+		reg.rrcn("E", mem)
 	case 0x0c:
-		reg.rrcn("H")
+		reg.rrcn("H", mem)
 	case 0x0d:
-		reg.rrcn("L")
+		reg.rrcn("L", mem)
 	case 0x0e:
-		reg.rrcn("HL")
+		reg.rrcn("HL", mem)
 	case 0x0f:
-		reg.rrcn("A")
+		reg.rrcn("A", mem)
 	case 0x10:
-		reg.rln("B")
+		reg.rln("B", mem)
 	case 0x11:
-		reg.rln("C")
+		reg.rln("C", mem)
 	case 0x12:
-		reg.rln("D")
+		reg.rln("D", mem)
 	case 0x13:
-		reg.rln("E")
+		reg.rln("E", mem)
 	case 0x14:
-		reg.rln("H")
+		reg.rln("H", mem)
 	case 0x15:
-		reg.rln("L")
+		reg.rln("L", mem)
 	case 0x16:
-		reg.rln("HL")
+		reg.rln("HL", mem)
 	case 0x17:
-		reg.rln("A")
+		reg.rln("A", mem)
 	case 0x18:
-		reg.rrn("B")
+		reg.rrn("B", mem)
 	case 0x19:
-		reg.rrn("C")
+		reg.rrn("C", mem)
 	case 0x1a:
-		reg.rrn("D")
+		reg.rrn("D", mem)
 	case 0x1b:
-		reg.rrn("E")
+		reg.rrn("E", mem)
 	case 0x1c:
-		reg.rrn("H")
+		reg.rrn("H", mem)
 	case 0x1d:
-		reg.rrn("L")
+		reg.rrn("L", mem)
 	case 0x1e:
-		reg.rrn("HL")
+		reg.rrn("HL", mem)
 	case 0x1f:
-		reg.rrn("A")
+		reg.rrn("A", mem)
 	case 0x20:
-		reg.slan("B")
+		reg.slan("B", mem)
 	case 0x21:
-		reg.slan("C")
+		reg.slan("C", mem)
 	case 0x22:
-		reg.slan("D")
+		reg.slan("D", mem)
 	case 0x23:
-		reg.slan("E")
+		reg.slan("E", mem)
 	case 0x24:
-		reg.slan("H")
+		reg.slan("H", mem)
 	case 0x25:
-		reg.slan("L")
+		reg.slan("L", mem)
 	case 0x26:
-		reg.slan("HL")
+		reg.slan("HL", mem)
 	case 0x27:
-		reg.slan("A")
+		reg.slan("A", mem)
 	case 0x28:
-		reg.sran("B")
+		reg.sran("B", mem)
 	case 0x29:
-		reg.sran("C")
+		reg.sran("C", mem)
 	case 0x2a:
-		reg.sran("D")
+		reg.sran("D", mem)
 	case 0x2b:
-		reg.sran("E")
+		reg.sran("E", mem)
 	case 0x2c:
-		reg.sran("H")
+		reg.sran("H", mem)
 	case 0x2d:
-		reg.sran("L")
+		reg.sran("L", mem)
 	case 0x2e:
-		reg.sran("HL")
+		reg.sran("HL", mem)
 	case 0x2f:
-		reg.sran("A")
+		reg.sran("A", mem)
 	case 0x30:
-		reg.swapn("B")
+		reg.swapn("B", mem)
 	case 0x31:
-		reg.swapn("C")
+		reg.swapn("C", mem)
 	case 0x32:
-		reg.swapn("D")
+		reg.swapn("D", mem)
 	case 0x33:
-		reg.swapn("E")
+		reg.swapn("E", mem)
 	case 0x34:
-		reg.swapn("H")
+		reg.swapn("H", mem)
 	case 0x35:
-		reg.swapn("L")
+		reg.swapn("L", mem)
 	case 0x36:
-		reg.swapn("HL")
+		reg.swapn("HL", mem)
 	case 0x37:
-		reg.swapn("A")
+		reg.swapn("A", mem)
 	case 0x38:
-		reg.srln("B")
+		reg.srln("B", mem)
 	case 0x39:
-		reg.srln("C")
+		reg.srln("C", mem)
 	case 0x3a:
-		reg.srln("D")
+		reg.srln("D", mem)
 	case 0x3b:
-		reg.srln("E")
+		reg.srln("E", mem)
 	case 0x3c:
-		reg.srln("H")
+		reg.srln("H", mem)
 	case 0x3d:
-		reg.srln("L")
+		reg.srln("L", mem)
 	case 0x3e:
-		reg.srln("HL")
+		reg.srln("HL", mem)
 	case 0x3f:
-		reg.srln("A")
+		reg.srln("A", mem)
 	case 0x40:
-		reg.bitBr("B", 0)
+		reg.bitBr("B", 0, mem)
 	case 0x41:
-		reg.bitBr("C", 0)
+		reg.bitBr("C", 0, mem)
 	case 0x42:
-		reg.bitBr("D", 0)
+		reg.bitBr("D", 0, mem)
 	case 0x43:
-		reg.bitBr("E", 0)
+		reg.bitBr("E", 0, mem)
 	case 0x44:
-		reg.bitBr("H", 0)
+		reg.bitBr("H", 0, mem)
 	case 0x45:
-		reg.bitBr("L", 0)
+		reg.bitBr("L", 0, mem)
 	case 0x46:
-		reg.bitBr("HL", 0)
+		reg.bitBr("HL", 0, mem)
 	case 0x47:
-		reg.bitBr("A", 0)
+		reg.bitBr("A", 0, mem)
 	case 0x48:
-		reg.bitBr("B", 1)
+		reg.bitBr("B", 1, mem)
 	case 0x49:
-		reg.bitBr("C", 1)
+		reg.bitBr("C", 1, mem)
 	case 0x4a:
-		reg.bitBr("D", 1)
+		reg.bitBr("D", 1, mem)
 	case 0x4b:
-		reg.bitBr("E", 1)
+		reg.bitBr("E", 1, mem)
 	case 0x4c:
-		reg.bitBr("H", 1)
+		reg.bitBr("H", 1, mem)
 	case 0x4d:
-		reg.bitBr("L", 1)
+		reg.bitBr("L", 1, mem)
 	case 0x4e:
-		reg.bitBr("HL", 1)
+		reg.bitBr("HL", 1, mem)
 	case 0x4f:
-		reg.bitBr("A", 1)
+		reg.bitBr("A", 1, mem)
 	case 0x50:
-		reg.bitBr("B", 2)
+		reg.bitBr("B", 2, mem)
 	case 0x51:
-		reg.bitBr("C", 2)
+		reg.bitBr("C", 2, mem)
 	case 0x52:
-		reg.bitBr("D", 2)
+		reg.bitBr("D", 2, mem)
 	case 0x53:
-		reg.bitBr("E", 2)
+		reg.bitBr("E", 2, mem)
 	case 0x54:
-		reg.bitBr("H", 2)
+		reg.bitBr("H", 2, mem)
 	case 0x55:
-		reg.bitBr("L", 2)
+		reg.bitBr("L", 2, mem)
 	case 0x56:
-		reg.bitBr("HL", 2)
+		reg.bitBr("HL", 2, mem)
 	case 0x57:
-		reg.bitBr("A", 2)
+		reg.bitBr("A", 2, mem)
 	case 0x58:
-		reg.bitBr("B", 3)
+		reg.bitBr("B", 3, mem)
 	case 0x59:
-		reg.bitBr("C", 3)
+		reg.bitBr("C", 3, mem)
 	case 0x5a:
-		reg.bitBr("D", 3)
+		reg.bitBr("D", 3, mem)
 	case 0x5b:
-		reg.bitBr("E", 3)
+		reg.bitBr("E", 3, mem)
 	case 0x5c:
-		reg.bitBr("H", 3)
+		reg.bitBr("H", 3, mem)
 	case 0x5d:
-		reg.bitBr("L", 3)
+		reg.bitBr("L", 3, mem)
 	case 0x5e:
-		reg.bitBr("HL", 3)
+		reg.bitBr("HL", 3, mem)
 	case 0x5f:
-		reg.bitBr("A", 3)
+		reg.bitBr("A", 3, mem)
 	case 0x60:
-		reg.bitBr("B", 4)
+		reg.bitBr("B", 4, mem)
 	case 0x61:
-		reg.bitBr("C", 4)
+		reg.bitBr("C", 4, mem)
 	case 0x62:
-		reg.bitBr("D", 4)
+		reg.bitBr("D", 4, mem)
 	case 0x63:
-		reg.bitBr("E", 4)
+		reg.bitBr("E", 4, mem)
 	case 0x64:
-		reg.bitBr("H", 4)
+		reg.bitBr("H", 4, mem)
 	case 0x65:
-		reg.bitBr("L", 4)
+		reg.bitBr("L", 4, mem)
 	case 0x66:
-		reg.bitBr("HL", 4)
+		reg.bitBr("HL", 4, mem)
 	case 0x67:
-		reg.bitBr("A", 4)
+		reg.bitBr("A", 4, mem)
 	case 0x68:
-		reg.bitBr("B", 5)
+		reg.bitBr("B", 5, mem)
 	case 0x69:
-		reg.bitBr("C", 5)
+		reg.bitBr("C", 5, mem)
 	case 0x6a:
-		reg.bitBr("D", 5)
+		reg.bitBr("D", 5, mem)
 	case 0x6b:
-		reg.bitBr("E", 5)
+		reg.bitBr("E", 5, mem)
 	case 0x6c:
-		reg.bitBr("H", 5)
+		reg.bitBr("H", 5, mem)
 	case 0x6d:
-		reg.bitBr("L", 5)
+		reg.bitBr("L", 5, mem)
 	case 0x6e:
-		reg.bitBr("HL", 5)
+		reg.bitBr("HL", 5, mem)
 	case 0x6f:
-		reg.bitBr("A", 5)
+		reg.bitBr("A", 5, mem)
 	case 0x70:
-		reg.bitBr("B", 6)
+		reg.bitBr("B", 6, mem)
 	case 0x71:
-		reg.bitBr("C", 6)
+		reg.bitBr("C", 6, mem)
 	case 0x72:
-		reg.bitBr("D", 6)
+		reg.bitBr("D", 6, mem)
 	case 0x73:
-		reg.bitBr("E", 6)
+		reg.bitBr("E", 6, mem)
 	case 0x74:
-		reg.bitBr("H", 6)
+		reg.bitBr("H", 6, mem)
 	case 0x75:
-		reg.bitBr("L", 6)
+		reg.bitBr("L", 6, mem)
 	case 0x76:
-		reg.bitBr("HL", 6)
+		reg.bitBr("HL", 6, mem)
 	case 0x77:
-		reg.bitBr("A", 6)
+		reg.bitBr("A", 6, mem)
 	case 0x78:
-		reg.bitBr("B", 7)
+		reg.bitBr("B", 7, mem)
 	case 0x79:
-		reg.bitBr("C", 7)
+		reg.bitBr("C", 7, mem)
 	case 0x7a:
-		reg.bitBr("D", 7)
+		reg.bitBr("D", 7, mem)
 	case 0x7b:
-		reg.bitBr("E", 7)
+		reg.bitBr("E", 7, mem)
 	case 0x7c:
-		reg.bitBr("H", 7)
+		reg.bitBr("H", 7, mem)
 	case 0x7d:
-		reg.bitBr("L", 7)
+		reg.bitBr("L", 7, mem)
 	case 0x7e:
-		reg.bitBr("HL", 7)
+		reg.bitBr("HL", 7, mem)
 	case 0x7f:
-		reg.bitBr("A", 7)
+		reg.bitBr("A", 7, mem)
 	case 0x80:
-		reg.resBr("B", 0)
+		reg.resBr("B", 0, mem)
 	case 0x81:
-		reg.resBr("C", 0)
+		reg.resBr("C", 0, mem)
 	case 0x82:
-		reg.resBr("D", 0)
+		reg.resBr("D", 0, mem)
 	case 0x83:
-		reg.resBr("E", 0)
+		reg.resBr("E", 0, mem)
 	case 0x84:
-		reg.resBr("H", 0)
+		reg.resBr("H", 0, mem)
 	case 0x85:
-		reg.resBr("L", 0)
+		reg.resBr("L", 0, mem)
 	case 0x86:
-		reg.resBr("HL", 0)
+		reg.resBr("HL", 0, mem)
 	case 0x87:
-		reg.resBr("A", 0)
+		reg.resBr("A", 0, mem)
 	case 0x88:
-		reg.resBr("B", 1)
+		reg.resBr("B", 1, mem)
 	case 0x89:
-		reg.resBr("C", 1)
+		reg.resBr("C", 1, mem)
 	case 0x8a:
-		reg.resBr("D", 1)
+		reg.resBr("D", 1, mem)
 	case 0x8b:
-		reg.resBr("E", 1)
+		reg.resBr("E", 1, mem)
 	case 0x8c:
-		reg.resBr("H", 1)
+		reg.resBr("H", 1, mem)
 	case 0x8d:
-		reg.resBr("L", 1)
+		reg.resBr("L", 1, mem)
 	case 0x8e:
-		reg.resBr("HL", 1)
+		reg.resBr("HL", 1, mem)
 	case 0x8f:
-		reg.resBr("A", 1)
+		reg.resBr("A", 1, mem)
 	case 0x90:
-		reg.resBr("B", 2)
+		reg.resBr("B", 2, mem)
 	case 0x91:
-		reg.resBr("C", 2)
+		reg.resBr("C", 2, mem)
 	case 0x92:
-		reg.resBr("D", 2)
+		reg.resBr("D", 2, mem)
 	case 0x93:
-		reg.resBr("E", 2)
+		reg.resBr("E", 2, mem)
 	case 0x94:
-		reg.resBr("H", 2)
+		reg.resBr("H", 2, mem)
 	case 0x95:
-		reg.resBr("L", 2)
+		reg.resBr("L", 2, mem)
 	case 0x96:
-		reg.resBr("HL", 2)
+		reg.resBr("HL", 2, mem)
 	case 0x97:
-		reg.resBr("A", 2)
+		reg.resBr("A", 2, mem)
 	case 0x98:
-		reg.resBr("B", 3)
+		reg.resBr("B", 3, mem)
 	case 0x99:
-		reg.resBr("C", 3)
+		reg.resBr("C", 3, mem)
 	case 0x9a:
-		reg.resBr("D", 3)
+		reg.resBr("D", 3, mem)
 	case 0x9b:
-		reg.resBr("E", 3)
+		reg.resBr("E", 3, mem)
 	case 0x9c:
-		reg.resBr("H", 3)
+		reg.resBr("H", 3, mem)
 	case 0x9d:
-		reg.resBr("L", 3)
+		reg.resBr("L", 3, mem)
 	case 0x9e:
-		reg.resBr("HL", 3)
+		reg.resBr("HL", 3, mem)
 	case 0x9f:
-		reg.resBr("A", 3)
+		reg.resBr("A", 3, mem)
 	case 0xa0:
-		reg.resBr("B", 4)
+		reg.resBr("B", 4, mem)
 	case 0xa1:
-		reg.resBr("C", 4)
+		reg.resBr("C", 4, mem)
 	case 0xa2:
-		reg.resBr("D", 4)
+		reg.resBr("D", 4, mem)
 	case 0xa3:
-		reg.resBr("E", 4)
+		reg.resBr("E", 4, mem)
 	case 0xa4:
-		reg.resBr("H", 4)
+		reg.resBr("H", 4, mem)
 	case 0xa5:
-		reg.resBr("L", 4)
+		reg.resBr("L", 4, mem)
 	case 0xa6:
-		reg.resBr("HL", 4)
+		reg.resBr("HL", 4, mem)
 	case 0xa7:
-		reg.resBr("A", 4)
+		reg.resBr("A", 4, mem)
 	case 0xa8:
-		reg.resBr("B", 5)
+		reg.resBr("B", 5, mem)
 	case 0xa9:
-		reg.resBr("C", 5)
+		reg.resBr("C", 5, mem)
 	case 0xaa:
-		reg.resBr("D", 5)
+		reg.resBr("D", 5, mem)
 	case 0xab:
-		reg.resBr("E", 5)
+		reg.resBr("E", 5, mem)
 	case 0xac:
-		reg.resBr("H", 5)
+		reg.resBr("H", 5, mem)
 	case 0xad:
-		reg.resBr("L", 5)
+		reg.resBr("L", 5, mem)
 	case 0xae:
-		reg.resBr("HL", 5)
+		reg.resBr("HL", 5, mem)
 	case 0xaf:
-		reg.resBr("A", 5)
+		reg.resBr("A", 5, mem)
 	case 0xb0:
-		reg.resBr("B", 6)
+		reg.resBr("B", 6, mem)
 	case 0xb1:
-		reg.resBr("C", 6)
+		reg.resBr("C", 6, mem)
 	case 0xb2:
-		reg.resBr("D", 6)
+		reg.resBr("D", 6, mem)
 	case 0xb3:
-		reg.resBr("E", 6)
+		reg.resBr("E", 6, mem)
 	case 0xb4:
-		reg.resBr("H", 6)
+		reg.resBr("H", 6, mem)
 	case 0xb5:
-		reg.resBr("L", 6)
+		reg.resBr("L", 6, mem)
 	case 0xb6:
-		reg.resBr("HL", 6)
+		reg.resBr("HL", 6, mem)
 	case 0xb7:
-		reg.resBr("A", 6)
+		reg.resBr("A", 6, mem)
 	case 0xb8:
-		reg.resBr("B", 7)
+		reg.resBr("B", 7, mem)
 	case 0xb9:
-		reg.resBr("C", 7)
+		reg.resBr("C", 7, mem)
 	case 0xba:
-		reg.resBr("D", 7)
+		reg.resBr("D", 7, mem)
 	case 0xbb:
-		reg.resBr("E", 7)
+		reg.resBr("E", 7, mem)
 	case 0xbc:
-		reg.resBr("H", 7)
+		reg.resBr("H", 7, mem)
 	case 0xbd:
-		reg.resBr("L", 7)
+		reg.resBr("L", 7, mem)
 	case 0xbe:
-		reg.resBr("HL", 7)
+		reg.resBr("HL", 7, mem)
 	case 0xbf:
-		reg.setBr("A", 7)
+		reg.resBr("A", 7, mem)
 	case 0xc0:
-		reg.setBr("B", 0)
+		reg.setBr("B", 0, mem)
 	case 0xc1:
-		reg.setBr("C", 0)
+		reg.setBr("C", 0, mem)
 	case 0xc2:
-		reg.setBr("D", 0)
+		reg.setBr("D", 0, mem)
 	case 0xc3:
-		reg.setBr("E", 0)
+		reg.setBr("E", 0, mem)
 	case 0xc4:
-		reg.setBr("H", 0)
+		reg.setBr("H", 0, mem)
 	case 0xc5:
-		reg.setBr("L", 0)
+		reg.setBr("L", 0, mem)
 	case 0xc6:
-		reg.setBr("HL", 0)
+		reg.setBr("HL", 0, mem)
 	case 0xc7:
-		reg.setBr("A", 0)
+		reg.setBr("A", 0, mem)
 	case 0xc8:
-		reg.setBr("B", 1)
+		reg.setBr("B", 1, mem)
 	case 0xc9:
-		reg.setBr("C", 1)
+		reg.setBr("C", 1, mem)
 	case 0xca:
-		reg.setBr("D", 1)
+		reg.setBr("D", 1, mem)
 	case 0xcb:
-		reg.setBr("E", 1)
+		reg.setBr("E", 1, mem)
 	case 0xcc:
-		reg.setBr("H", 1)
+		reg.setBr("H", 1, mem)
 	case 0xcd:
-		reg.setBr("L", 1)
+		reg.setBr("L", 1, mem)
 	case 0xce:
-		reg.setBr("HL", 1)
+		reg.setBr("HL", 1, mem)
 	case 0xcf:
-		reg.setBr("A", 1)
+		reg.setBr("A", 1, mem)
 	case 0xd0:
-		reg.setBr("B", 2)
+		reg.setBr("B", 2, mem)
 	case 0xd1:
-		reg.setBr("C", 2)
+		reg.setBr("C", 2, mem)
 	case 0xd2:
-		reg.setBr("D", 2)
+		reg.setBr("D", 2, mem)
 	case 0xd3:
-		reg.setBr("E", 2)
+		reg.setBr("E", 2, mem)
 	case 0xd4:
-		reg.setBr("H", 2)
+		reg.setBr("H", 2, mem)
 	case 0xd5:
-		reg.setBr("L", 2)
+		reg.setBr("L", 2, mem)
 	case 0xd6:
-		reg.setBr("HL", 2)
+		reg.setBr("HL", 2, mem)
 	case 0xd7:
-		reg.setBr("A", 2)
+		reg.setBr("A", 2, mem)
 	case 0xd8:
-		reg.setBr("B", 3)
+		reg.setBr("B", 3, mem)
 	case 0xd9:
-		reg.setBr("C", 3)
+		reg.setBr("C", 3, mem)
 	case 0xda:
-		reg.setBr("D", 3)
+		reg.setBr("D", 3, mem)
 	case 0xdb:
-		reg.setBr("E", 3)
+		reg.setBr("E", 3, mem)
 	case 0xdc:
-		reg.setBr("H", 3)
+		reg.setBr("H", 3, mem)
 	case 0xdd:
-		reg.setBr("L", 3)
+		reg.setBr("L", 3, mem)
 	case 0xde:
-		reg.setBr("HL", 3)
+		reg.setBr("HL", 3, mem)
 	case 0xdf:
-		reg.setBr("A", 3)
+		reg.setBr("A", 3, mem)
 	case 0xe0:
-		reg.setBr("B", 4)
+		reg.setBr("B", 4, mem)
 	case 0xe1:
-		reg.setBr("C", 4)
+		reg.setBr("C", 4, mem)
 	case 0xe2:
-		reg.setBr("D", 4)
+		reg.setBr("D", 4, mem)
 	case 0xe3:
-		reg.setBr("E", 4)
+		reg.setBr("E", 4, mem)
 	case 0xe4:
-		reg.setBr("H", 4)
+		reg.setBr("H", 4, mem)
 	case 0xe5:
-		reg.setBr("L", 4)
+		reg.setBr("L", 4, mem)
 	case 0xe6:
-		reg.setBr("HL", 4)
+		reg.setBr("HL", 4, mem)
 	case 0xe7:
-		reg.setBr("A", 4)
+		reg.setBr("A", 4, mem)
 	case 0xe8:
-		reg.setBr("B", 5)
+		reg.setBr("B", 5, mem)
 	case 0xe9:
-		reg.setBr("C", 5)
+		reg.setBr("C", 5, mem)
 	case 0xea:
-		reg.setBr("D", 5)
+		reg.setBr("D", 5, mem)
 	case 0xeb:
-		reg.setBr("E", 5)
+		reg.setBr("E", 5, mem)
 	case 0xec:
-		reg.setBr("H", 5)
+		reg.setBr("H", 5, mem)
 	case 0xed:
-		reg.setBr("L", 5)
+		reg.setBr("L", 5, mem)
 	case 0xee:
-		reg.setBr("HL", 5)
+		reg.setBr("HL", 5, mem)
 	case 0xef:
-		reg.setBr("A", 5)
+		reg.setBr("A", 5, mem)
 	case 0xf0:
-		reg.setBr("B", 6)
+		reg.setBr("B", 6, mem)
 	case 0xf1:
-		reg.setBr("C", 6)
+		reg.setBr("C", 6, mem)
 	case 0xf2:
-		reg.setBr("D", 6)
+		reg.setBr("D", 6, mem)
 	case 0xf3:
-		reg.setBr("E", 6)
+		reg.setBr("E", 6, mem)
 	case 0xf4:
-		reg.setBr("H", 6)
+		reg.setBr("H", 6, mem)
 	case 0xf5:
-		reg.setBr("L", 6)
+		reg.setBr("L", 6, mem)
 	case 0xf6:
-		reg.setBr("HL", 6)
+		reg.setBr("HL", 6, mem)
 	case 0xf7:
-		reg.setBr("A", 6)
+		reg.setBr("A", 6, mem)
 	case 0xf8:
-		reg.setBr("B", 7)
+		reg.setBr("B", 7, mem)
 	case 0xf9:
-		reg.setBr("C", 7)
+		reg.setBr("C", 7, mem)
 	case 0xfa:
-		reg.setBr("D", 7)
+		reg.setBr("D", 7, mem)
 	case 0xfb:
-		reg.setBr("E", 7)
+		reg.setBr("E", 7, mem)
 	case 0xfc:
-		reg.setBr("H", 7)
+		reg.setBr("H", 7, mem)
 	case 0xfd:
-		reg.setBr("L", 7)
+		reg.setBr("L", 7, mem)
 	case 0xfe:
-		reg.setBr("HL", 7)
+		reg.setBr("HL", 7, mem)
 	case 0xff:
-		reg.setBr("A", 7)
+		reg.setBr("A", 7, mem)
 	}
 }
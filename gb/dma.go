@@ -0,0 +1,58 @@
+package gb
+
+// OAM DMA transfer, copying 160 bytes from XX00-XX9F to OAM at one byte per M-cycle
+// see https://gbdev.io/pandocs/OAM_DMA_Transfer.html
+type Dma struct {
+	// a transfer is in progress, restricting CPU memory accesses
+	active bool
+	source uint16
+	// index of the next byte to copy
+	index int
+	// cycles since the last byte was copied
+	cycles int
+	// cycles before a requested transfer begins, and its source
+	starting       int
+	startingSource uint16
+}
+
+// Request a transfer from address value * 0x100. The transfer begins after one M-cycle,
+// a transfer already in progress continues until then.
+func (dma *Dma) start(value byte) {
+	dma.starting = 4
+	dma.startingSource = uint16(value) << 8
+}
+
+// Advance the transfer by the given number of cycles
+func (dma *Dma) step(cycles int, mem *Memory) {
+	for i := 0; i < cycles; i++ {
+		if dma.active {
+			dma.cycles++
+			if dma.cycles == 4 {
+				dma.cycles = 0
+				dma.copyByte(mem)
+			}
+		}
+		if dma.starting > 0 {
+			dma.starting--
+			if dma.starting == 0 {
+				dma.active = true
+				dma.source = dma.startingSource
+				dma.index = 0
+				dma.cycles = 0
+			}
+		}
+	}
+}
+
+func (dma *Dma) copyByte(mem *Memory) {
+	address := dma.source + uint16(dma.index)
+	// sources above 0xE000 read the work RAM echo
+	if address >= 0xE000 {
+		address -= 0x2000
+	}
+	mem.oam[dma.index] = mem.peekByte(address)
+	dma.index++
+	if dma.index == 160 {
+		dma.active = false
+	}
+}
@@ -0,0 +1,118 @@
+package gb
+
+import "testing"
+
+// Write the program in HRAM, the only memory the CPU can fetch from during DMA
+func loadHramProgram(reg *Register, mem *Memory, program []byte) {
+	for i, value := range program {
+		mem.writeByte(0xff80+uint16(i), value)
+	}
+	reg.pc = 0xff80
+}
+
+// Execute count instructions, clocking DMA with the given cycles per instruction
+func runDma(reg *Register, mem *Memory, count int, cycles int) {
+	for i := 0; i < count; i++ {
+		reg.execute(mem.readByte(reg.pc), mem)
+		mem.dma.step(cycles, mem)
+	}
+}
+
+func TestDma(t *testing.T) {
+	var reg Register
+	var mem Memory
+	for i := uint16(0); i < 160; i++ {
+		mem.writeByte(0xc100+i, byte(i+1))
+	}
+	// LD A,0xC1; LDH (0x46),A; NOP...
+	loadHramProgram(&reg, &mem, []byte{0x3e, 0xc1, 0xe0, 0x46})
+	runDma(&reg, &mem, 2, 4)
+	if !mem.dma.active {
+		t.Fatalf("DMA not started")
+	}
+	if mem.readByte(0xc100) != 0xff || mem.readByte(0xfe00) != 0xff {
+		t.Errorf("CPU can read outside HRAM during DMA")
+	}
+	if mem.readByte(0xff81) != 0xc1 {
+		t.Errorf("CPU can not read HRAM during DMA")
+	}
+	mem.writeByte(0xc000, 0x12)
+	if mem.wram[0] != 0 {
+		t.Errorf("CPU can write outside HRAM during DMA")
+	}
+	mem.dma.step(159*4, &mem)
+	if !mem.dma.active {
+		t.Errorf("DMA finished before 160 M-cycles")
+	}
+	mem.dma.step(4, &mem)
+	if mem.dma.active {
+		t.Fatalf("DMA not finished after 160 M-cycles")
+	}
+	for i := uint16(0); i < 160; i++ {
+		if mem.readByte(0xfe00+i) != byte(i+1) {
+			t.Fatalf("0x%02x in OAM at 0x%04x, expected 0x%02x", mem.readByte(0xfe00+i), 0xfe00+i, i+1)
+		}
+	}
+}
+
+func TestDmaRestart(t *testing.T) {
+	var reg Register
+	var mem Memory
+	for i := uint16(0); i < 160; i++ {
+		mem.writeByte(0xc100+i, 0x11)
+		mem.writeByte(0xc200+i, 0x22)
+	}
+	// LD A,0xC1; LDH (0x46),A; LD A,0xC2; LDH (0x46),A
+	loadHramProgram(&reg, &mem, []byte{0x3e, 0xc1, 0xe0, 0x46, 0x3e, 0xc2, 0xe0, 0x46})
+	runDma(&reg, &mem, 2, 4)
+	// let the first transfer copy 10 bytes before restarting
+	mem.dma.step(10*4, &mem)
+	runDma(&reg, &mem, 2, 4)
+	if !mem.dma.active || mem.dma.source != 0xc200 {
+		t.Fatalf("DMA not restarted")
+	}
+	mem.dma.step(160*4, &mem)
+	if mem.dma.active {
+		t.Fatalf("DMA not finished")
+	}
+	for i := uint16(0); i < 160; i++ {
+		if mem.oam[i] != 0x22 {
+			t.Fatalf("0x%02x in OAM at index %d, expected 0x22", mem.oam[i], i)
+		}
+	}
+}
+
+func TestDmaRoutine(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	// CALL 0xFF80; JR -2
+	copy(rom[0x150:], []byte{0xcd, 0x80, 0xff, 0x18, 0xfe})
+	emu, err := New(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the routine games copy to HRAM: LD A,0xC1; LDH (0x46),A; LD A,40;
+	// wait: DEC A; JR NZ,wait; RET
+	for i, value := range []byte{0x3e, 0xc1, 0xe0, 0x46, 0x3e, 0x28, 0x3d, 0x20, 0xfd, 0xc9} {
+		emu.mem.writeByte(0xff80+uint16(i), value)
+	}
+	for i := uint16(0); i < 160; i++ {
+		emu.mem.writeByte(0xc100+i, byte(i+1))
+	}
+	// stack in work RAM, which the CPU cannot read during DMA
+	emu.cpu.sp = 0xdffe
+	emu.cpu.pc = 0x150
+	for i := 0; i < 200 && emu.cpu.pc != 0x153; i++ {
+		emu.StepInstruction()
+	}
+	if emu.cpu.pc != 0x153 || emu.cpu.sp != 0xdffe {
+		t.Fatalf("returned to 0x%04x with SP 0x%04x, expected 0x0153 and 0xdffe", emu.cpu.pc, emu.cpu.sp)
+	}
+	if emu.mem.dma.active {
+		t.Errorf("DMA still in progress after the routine")
+	}
+	for i := uint16(0); i < 160; i++ {
+		if emu.mem.oam[i] != byte(i+1) {
+			t.Fatalf("0x%02x in OAM at 0x%04x, expected 0x%02x", emu.mem.oam[i], 0xfe00+i, i+1)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package gb
+
+import (
+	"fmt"
+	"time"
+)
+
+// Screen size, in pixels
+const (
+	ScreenWidth  int = 160
+	ScreenHeight int = 144
+)
+
+// Screen pixels by line, each pixel being a shade from 0 (white) to 3 (black)
+type Frame [ScreenHeight][ScreenWidth]byte
+
+// Set of joypad buttons, one bit per button
+type Buttons byte
+
+const (
+	ButtonRight  Buttons = 1 << buttonRight
+	ButtonLeft   Buttons = 1 << buttonLeft
+	ButtonUp     Buttons = 1 << buttonUp
+	ButtonDown   Buttons = 1 << buttonDown
+	ButtonA      Buttons = 1 << buttonA
+	ButtonB      Buttons = 1 << buttonB
+	ButtonSelect Buttons = 1 << buttonSelect
+	ButtonStart  Buttons = 1 << buttonStart
+)
+
+// Game Boy emulator running a cartridge
+type Emulator struct {
+	cpu     Register
+	mem     Memory
+	gpu     Gpu
+	battery *Battery
+	buttons Buttons
+	// last complete frame, copied from the PPU frame buffer on VBlank
+	frame Frame
+	// frontend, see Connect
+	video VideoSink
+	audio AudioSink
+	input InputSource
+	// settings of the options given to New
+	model      Model
+	bootRom    []byte
+	fifo       bool
+	sampleRate int
+	savePath   string
+}
+
+// Option of New
+type Option func(*Emulator)
+
+// Emulate hardware model, DMG by default
+func WithModel(model Model) Option {
+	return func(emu *Emulator) {
+		emu.model = model
+	}
+}
+
+// Run boot ROM data before the cartridge, instead of starting in the post-boot state of the model
+func WithBootRom(data []byte) Option {
+	return func(emu *Emulator) {
+		emu.bootRom = data
+	}
+}
+
+// Render with the cycle accurate pixel FIFO instead of the faster scanline renderer
+func WithPixelFifo() Option {
+	return func(emu *Emulator) {
+		emu.fifo = true
+	}
+}
+
+// Produce rate stereo audio samples per second, no samples are produced by default
+func WithSampleRate(rate int) Option {
+	return func(emu *Emulator) {
+		emu.sampleRate = rate
+	}
+}
+
+// Load and save battery backed cartridge RAM in the file at path
+func WithSaveFile(path string) Option {
+	return func(emu *Emulator) {
+		emu.savePath = path
+	}
+}
+
+// Returns an emulator running the cartridge ROM rom
+func New(rom []byte, opts ...Option) (*Emulator, error) {
+	emu := &Emulator{model: ModelDmg}
+	for _, opt := range opts {
+		opt(emu)
+	}
+	if err := emu.mem.loadRom(rom); err != nil {
+		return nil, err
+	}
+	emu.mem.cgb = emu.model == ModelCgb && emu.mem.cartridge.cgbFlag&0x80 != 0
+	if emu.fifo {
+		emu.gpu.fifo = &PixelFifo{}
+	}
+	if emu.bootRom != nil {
+		// the boot ROM starts at 0x0000 and initializes the hardware itself
+		if err := emu.mem.loadBootRom(emu.bootRom); err != nil {
+			return nil, err
+		}
+	} else {
+		postBoot(emu.model, &emu.cpu, &emu.mem, &emu.gpu)
+	}
+	if emu.sampleRate > 0 {
+		emu.mem.apu.setSampleRate(emu.sampleRate)
+	}
+	if emu.savePath != "" {
+		emu.battery = newBattery(emu.savePath, &emu.mem)
+		if emu.battery != nil {
+			if err := emu.battery.load(); err != nil {
+				return nil, fmt.Errorf("cannot load save file: %w", err)
+			}
+		}
+	}
+	return emu, nil
+}
+
+// Execute one instruction, or handle one interrupt, and advance the other
+// components by the same time. Returns the number of cycles elapsed.
+func (emu *Emulator) StepInstruction() int {
+	emu.cpu.step(&emu.mem)
+	// DIV is not clocked in STOP mode
+	if !emu.cpu.stopped {
+		emu.mem.timer.step(emu.cpu.clock, &emu.mem)
+	}
+	emu.mem.dma.step(emu.cpu.clock, &emu.mem)
+	emu.mem.serial.step(emu.cpu.clock, &emu.mem)
+	// in double speed mode, the PPU and APU run at half the speed of the CPU,
+	// timer, DMA and serial port
+	cycles := emu.cpu.clock
+	if emu.mem.doubleSpeed {
+		cycles /= 2
+	}
+	emu.mem.apu.step(cycles, &emu.mem)
+	emu.gpu.step(cycles, &emu.mem)
+	if emu.gpu.rendering {
+		for y := 0; y < ScreenHeight; y++ {
+			for x := 0; x < ScreenWidth; x++ {
+				emu.frame[y][x] = byte(emu.gpu.frame_buffer[x][y])
+			}
+		}
+	}
+	return emu.cpu.clock
+}
+
+// Run until the next frame is complete. Battery backed RAM is saved once it
+// stops changing, an error is returned when the save file cannot be written.
+func (emu *Emulator) RunFrame() error {
+	if emu.input != nil {
+		emu.SetButtons(emu.input.Buttons())
+	}
+	for {
+		emu.StepInstruction()
+		if emu.gpu.rendering {
+			break
+		}
+	}
+	if emu.video != nil {
+		emu.video.WriteFrame(emu.FrameBuffer())
+	}
+	if emu.audio != nil {
+		emu.audio.WriteSamples(emu.AudioSamples())
+	}
+	if emu.battery != nil {
+		return emu.battery.update(&emu.mem, time.Now())
+	}
+	return nil
+}
+
+// Returns the last complete frame, the frame being drawn is not visible before VBlank
+func (emu *Emulator) FrameBuffer() Frame {
+	return emu.frame
+}
+
+// Set the buttons currently pressed
+func (emu *Emulator) SetButtons(buttons Buttons) {
+	for button := byte(0); button < 8; button++ {
+		mask := Buttons(1) << button
+		if (emu.buttons^buttons)&mask != 0 {
+			emu.mem.joypad.setButton(button, buttons&mask != 0, &emu.mem)
+		}
+	}
+	emu.buttons = buttons
+}
+
+// Returns the audio samples produced since the last call, interleaved left and
+// right, between -1 and 1
+func (emu *Emulator) AudioSamples() []float32 {
+	return emu.mem.apu.readSamples()
+}
+
+// Returns the bytes sent through the serial port since the last call
+func (emu *Emulator) SerialOutput() []byte {
+	return emu.mem.serial.readOutput()
+}
+
+// Returns true while the motor of a rumble cartridge is on
+func (emu *Emulator) Rumble() bool {
+	mbc, ok := emu.mem.mbc.(*Mbc5)
+	return ok && mbc.rumbling
+}
+
+// Returns the 8x8 tiles of VRAM, as color numbers by line, for debugging
+func (emu *Emulator) VramTiles() [numtiles * 8][8]byte {
+	return emu.gpu.getVram(&emu.mem)
+}
+
+// Write battery backed RAM changes not saved yet
+func (emu *Emulator) Close() error {
+	if emu.battery != nil && (emu.battery.pending || emu.mem.ramWritten) {
+		return emu.battery.flush()
+	}
+	return nil
+}
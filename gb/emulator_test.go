@@ -0,0 +1,224 @@
+package gb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0), WithModel(ModelMgb))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if emu.cpu.pc != 0x0100 || emu.cpu.a != 0xff {
+		t.Errorf("emulator not in the MGB post-boot state")
+	}
+	if _, err := New(make([]byte, 0x100)); err == nil {
+		t.Errorf("no error for a truncated ROM")
+	}
+	if _, err := New(makeRom(0x00, 0, 0), WithBootRom(make([]byte, 10))); err == nil {
+		t.Errorf("no error for an invalid boot ROM")
+	}
+}
+
+func TestRunFrame(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// tile 0, used by the whole background map, is color 1: black with BGP 0xFC
+	for i := 0; i < 16; i += 2 {
+		emu.mem.vram[i] = 0xff
+	}
+	cycles := 0
+	for !emu.gpu.rendering {
+		cycles += emu.StepInstruction()
+	}
+	if err := emu.RunFrame(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	frame := emu.FrameBuffer()
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			if frame[y][x] != 3 {
+				t.Fatalf("%d shade at (%d, %d), expected 3", frame[y][x], x, y)
+			}
+		}
+	}
+	if cycles > 70224 {
+		t.Errorf("%d cycles before the first frame, expected at most 70224", cycles)
+	}
+}
+
+func TestSetButtons(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// select the action buttons
+	emu.mem.writeByte(0xff00, 0x10)
+	emu.SetButtons(ButtonA | ButtonStart)
+	if value := emu.mem.readByte(0xff00) & 0x0f; value != 0x06 {
+		t.Errorf("0x%x in the low nibble of P1, expected 0x6", value)
+	}
+	emu.SetButtons(ButtonStart)
+	if value := emu.mem.readByte(0xff00) & 0x0f; value != 0x07 {
+		t.Errorf("0x%x in the low nibble of P1, expected 0x7", value)
+	}
+}
+
+func TestAudioSamples(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0), WithSampleRate(48000))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.RunFrame()
+	emu.AudioSamples()
+	emu.RunFrame()
+	// a frame lasts 70224 cycles
+	if samples := len(emu.AudioSamples()); samples < 802*2 || samples > 805*2 {
+		t.Errorf("%d samples in a frame, expected about %d", samples, 803*2)
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.sav")
+	emu, err := New(makeRom(0x03, 0, 2), WithSaveFile(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.mem.writeByte(0x0000, 0x0a)
+	emu.mem.writeByte(0xa000, 0x42)
+	if err := emu.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || data[0] != 0x42 {
+		t.Errorf("external RAM not saved on close")
+	}
+	emu, err = New(makeRom(0x03, 0, 2), WithSaveFile(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.mem.writeByte(0x0000, 0x0a)
+	if emu.mem.readByte(0xa000) != 0x42 {
+		t.Errorf("external RAM not loaded from the save file")
+	}
+}
+
+func TestRunFrameStopped(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	// STOP
+	copy(rom[0x150:], []byte{0x10, 0x00})
+	emu, err := New(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.cpu.pc = 0x150
+	emu.StepInstruction()
+	if !emu.cpu.stopped {
+		t.Fatalf("CPU not stopped")
+	}
+	// frames are still completed while stopped
+	for i := 0; i < 2; i++ {
+		if err := emu.RunFrame(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	emu.SetButtons(ButtonA)
+	emu.StepInstruction()
+	if emu.cpu.stopped {
+		t.Errorf("CPU still stopped after a button press")
+	}
+}
+
+func TestFrameBufferComplete(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := emu.RunFrame(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// tile 0 turns black in the middle of the next frame
+	for emu.gpu.line != 72 {
+		emu.StepInstruction()
+	}
+	for i := 0; i < 16; i += 2 {
+		emu.mem.vram[i] = 0xff
+	}
+	for emu.gpu.line != 100 {
+		emu.StepInstruction()
+	}
+	frame := emu.FrameBuffer()
+	if frame[80][0] != 0 || frame[0][0] != 0 {
+		t.Errorf("lines of the frame being drawn returned before VBlank")
+	}
+	emu.RunFrame()
+	frame = emu.FrameBuffer()
+	if frame[0][0] != 0 || frame[80][0] != 3 {
+		t.Errorf("frame completed on VBlank not returned")
+	}
+}
+
+func TestRumble(t *testing.T) {
+	// MBC5+RUMBLE
+	emu, err := New(makeRom(0x1c, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if emu.Rumble() {
+		t.Errorf("rumble motor on at startup")
+	}
+	// bit 3 of the RAM bank register drives the motor
+	emu.mem.writeByte(0x4000, 0x08)
+	if !emu.Rumble() {
+		t.Errorf("rumble motor not started")
+	}
+	emu.mem.writeByte(0x4000, 0x00)
+	if emu.Rumble() {
+		t.Errorf("rumble motor not stopped")
+	}
+	emu, err = New(makeRom(0x00, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if emu.Rumble() {
+		t.Errorf("rumble without a rumble cartridge")
+	}
+}
+
+func TestDoubleSpeed(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	// CGB only cartridge: STOP; NOP...
+	rom[0x143] = 0xc0
+	copy(rom[0x150:], []byte{0x10, 0x00})
+	rom[0x14D] = computeHeaderChecksum(rom)
+	emu, err := New(rom, WithModel(ModelCgb))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.cpu.pc = 0x150
+	emu.mem.writeByte(0xff4d, 0x01)
+	emu.StepInstruction()
+	if !emu.mem.doubleSpeed || emu.cpu.stopped {
+		t.Fatalf("speed not switched by STOP")
+	}
+	// 456 PPU cycles per line take 912 CPU cycles
+	for emu.gpu.mode != 2 {
+		emu.StepInstruction()
+	}
+	line := emu.gpu.line
+	div := emu.mem.timer.counter
+	cycles := 0
+	for cycles < 912 {
+		cycles += emu.StepInstruction()
+	}
+	if emu.gpu.line != line+1 || emu.gpu.mode != 2 {
+		t.Errorf("PPU at line %d mode %d after 912 cycles, expected line %d mode 2", emu.gpu.line, emu.gpu.mode, line+1)
+	}
+	if emu.mem.timer.counter-div != 912 {
+		t.Errorf("DIV counter advanced by %d, expected 912", emu.mem.timer.counter-div)
+	}
+}
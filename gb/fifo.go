@@ -0,0 +1,295 @@
+package gb
+
+// Pixel FIFO renderer, modelling the background and sprite fetchers dot by dot.
+// It is slower than the scanline renderer but mode 3 has its real, variable
+// length and register writes during a line take effect at the right pixel.
+// see https://gbdev.io/pandocs/pixel_fifo.html
+type PixelFifo struct {
+	// dot of the current line, 0-455
+	dot int
+	// next pixel pushed to the LCD
+	x int
+	// pixels still to drop from the background FIFO, for SCX fine scrolling
+	discard int
+	// dots left of the fetch thrown away at the start of mode 3
+	delay      int
+	background []FifoPixel
+	objects    []FifoPixel
+	fetcher    Fetcher
+	// sprites of the current line not fetched yet, in X order
+	sprites []Sprite
+	// dots spent fetching sprites[0], 0 when no sprite is being fetched
+	sprite_dots int
+	// the fetcher reads the window instead of the background
+	window       bool
+	window_drawn bool
+}
+
+type FifoPixel struct {
+	color byte
+	// sprites only: OBP1 palette and background priority flags
+	palette  bool
+	priority bool
+}
+
+// Background fetcher states, every state but fetchPush takes 2 dots
+const (
+	fetchTile int = iota
+	fetchDataLow
+	fetchDataHigh
+	fetchPush
+)
+
+type Fetcher struct {
+	state int
+	dots  int
+	// tile column fetched, relative to SCX or to the window left edge
+	column int
+	tile   byte
+	low    byte
+	high   byte
+}
+
+func (fifo *PixelFifo) step(gpu *Gpu, cycles int, mem *Memory) {
+	gpu.rendering = false
+	for i := 0; i < cycles; i++ {
+		fifo.tick(gpu, mem)
+		gpu.updateStat(mem)
+	}
+}
+
+// Advance the PPU by one dot
+func (fifo *PixelFifo) tick(gpu *Gpu, mem *Memory) {
+	switch gpu.mode {
+	// Scanline (OAM access)
+	case 2:
+		if fifo.dot == 0 && gpu.line == int(mem.io[0x4a]) {
+			gpu.window_triggered = true
+		}
+		if fifo.dot == 79 {
+			fifo.startLine(gpu, mem)
+			gpu.mode = 3
+		}
+	// Scanline (VRAM access)
+	case 3:
+		fifo.render(gpu, mem)
+		if fifo.x == 160 {
+			gpu.mode = 0
+		}
+	}
+	fifo.dot++
+	if fifo.dot < 456 {
+		return
+	}
+	fifo.dot = 0
+	if fifo.window_drawn {
+		gpu.window_line++
+		fifo.window_drawn = false
+	}
+	gpu.line++
+	switch {
+	case gpu.line == 144:
+		// last vblank, render the framebuffer
+		gpu.mode = 1
+		gpu.rendering = true
+		mem.requestInterrupt(vblankInterrupt)
+	case gpu.line > 153:
+		// Restart scanning
+		gpu.mode = 2
+		gpu.line = 0
+		gpu.window_line = 0
+		gpu.window_triggered = false
+	case gpu.line < 144:
+		gpu.mode = 2
+	}
+}
+
+// Scan OAM and reset the FIFOs at the start of mode 3
+func (fifo *PixelFifo) startLine(gpu *Gpu, mem *Memory) {
+	gpu.setGpuControl(mem)
+	fifo.sprites = gpu.lineSprites(mem)
+	fifo.sprite_dots = 0
+	fifo.x = 0
+	fifo.discard = int(mem.io[0x43] & 0x07)
+	fifo.delay = 6
+	fifo.background = fifo.background[:0]
+	fifo.objects = fifo.objects[:0]
+	fifo.fetcher = Fetcher{}
+	fifo.window = false
+}
+
+// Run the fetchers and push at most one pixel to the LCD
+func (fifo *PixelFifo) render(gpu *Gpu, mem *Memory) {
+	lcdc := mem.io[0x40]
+	if fifo.delay > 0 {
+		fifo.delay--
+		return
+	}
+	// sprites stall the pixel output while they are fetched
+	for fifo.sprite_dots == 0 && len(fifo.sprites) > 0 && fifo.sprites[0].x <= fifo.x {
+		// sprites entirely left of the screen are skipped without fetching
+		if !hasBit(uint16(lcdc), 1) || fifo.sprites[0].x <= -8 {
+			fifo.sprites = fifo.sprites[1:]
+			continue
+		}
+		fifo.sprite_dots = 1
+	}
+	if fifo.sprite_dots > 0 {
+		// the background fetcher finishes its tile first
+		if fifo.fetcher.state != fetchPush {
+			fifo.fetch(gpu, mem)
+			return
+		}
+		fifo.sprite_dots++
+		if fifo.sprite_dots > 6 {
+			fifo.fetchSprite(gpu, mem, fifo.sprites[0])
+			fifo.sprites = fifo.sprites[1:]
+			fifo.sprite_dots = 0
+		}
+		return
+	}
+	// the window starts when the LCD reaches WX-7, with WX<7 at the left edge
+	wx := int(mem.io[0x4b])
+	start := wx - 7
+	if start < 0 {
+		start = 0
+	}
+	if !fifo.window && gpu.window_triggered && lcdc&0x21 == 0x21 && wx <= 166 && fifo.x == start {
+		// switching to the window restarts the fetcher on an empty FIFO
+		fifo.window = true
+		fifo.window_drawn = true
+		fifo.background = fifo.background[:0]
+		fifo.fetcher = Fetcher{}
+		fifo.discard = 0
+		if wx < 7 {
+			fifo.discard = 7 - wx
+		}
+	}
+	fifo.fetch(gpu, mem)
+	fifo.shift(gpu, mem)
+}
+
+// Advance the background fetcher by one dot
+func (fifo *PixelFifo) fetch(gpu *Gpu, mem *Memory) {
+	fetcher := &fifo.fetcher
+	lcdc := mem.io[0x40]
+	if fetcher.state == fetchPush {
+		if len(fifo.background) == 0 {
+			for bit := 7; bit >= 0; bit-- {
+				fifo.background = append(fifo.background, FifoPixel{color: tileColor(fetcher.low, fetcher.high, bit)})
+			}
+			fetcher.column++
+			fetcher.state = fetchTile
+		}
+		return
+	}
+	fetcher.dots++
+	if fetcher.dots < 2 {
+		return
+	}
+	fetcher.dots = 0
+
+	// SCY and SCX are read on every fetch, so changes apply to the next tile
+	var map_region uint16 = 0x9800
+	var y, column int
+	if fifo.window {
+		if hasBit(uint16(lcdc), 6) {
+			map_region = 0x9C00
+		}
+		y = gpu.window_line
+		column = fetcher.column
+	} else {
+		if hasBit(uint16(lcdc), 3) {
+			map_region = 0x9C00
+		}
+		y = (gpu.line + int(mem.io[0x42])) & 0xFF
+		column = (int(mem.io[0x43])/8 + fetcher.column) & 0x1F
+	}
+	address := tileDataAddress(fetcher.tile, hasBit(uint16(lcdc), 4)) + uint16(y%8)*2
+	switch fetcher.state {
+	case fetchTile:
+		fetcher.tile = mem.vram[map_region-0x8000+uint16(y/8)*32+uint16(column)]
+	case fetchDataLow:
+		fetcher.low = mem.vram[address-0x8000]
+	case fetchDataHigh:
+		fetcher.high = mem.vram[address-0x8000+1]
+	}
+	fetcher.state++
+}
+
+// Merge the pixels of sprite into the sprite FIFO. Pixels of sprites fetched
+// before have priority unless they are transparent.
+func (fifo *PixelFifo) fetchSprite(gpu *Gpu, mem *Memory, sprite Sprite) {
+	height := 8
+	if hasBit(uint16(mem.io[0x40]), 2) {
+		height = 16
+	}
+	row := gpu.line - sprite.y
+	// Y flip
+	if hasBit(uint16(sprite.flags), 6) {
+		row = height - 1 - row
+	}
+	tile := sprite.tile
+	if height == 16 {
+		tile = (tile & 0xFE) + byte(row/8)
+	}
+	address := uint16(tile)*16 + uint16(row%8)*2
+	data_byte_1 := mem.vram[address]
+	data_byte_2 := mem.vram[address+1]
+	for len(fifo.objects) < 8 {
+		fifo.objects = append(fifo.objects, FifoPixel{})
+	}
+	// pixels left of the screen are dropped
+	skip := fifo.x - sprite.x
+	for column := skip; column < 8; column++ {
+		bit := 7 - column
+		// X flip
+		if hasBit(uint16(sprite.flags), 5) {
+			bit = column
+		}
+		pixel := &fifo.objects[column-skip]
+		if pixel.color != 0 {
+			continue
+		}
+		*pixel = FifoPixel{
+			color:    tileColor(data_byte_1, data_byte_2, bit),
+			palette:  hasBit(uint16(sprite.flags), 4),
+			priority: hasBit(uint16(sprite.flags), 7),
+		}
+	}
+}
+
+// Mix the next pixels of both FIFOs and push the result to the LCD
+func (fifo *PixelFifo) shift(gpu *Gpu, mem *Memory) {
+	if len(fifo.background) == 0 {
+		return
+	}
+	background := fifo.background[0]
+	fifo.background = fifo.background[1:]
+	if fifo.discard > 0 {
+		fifo.discard--
+		return
+	}
+	var object FifoPixel
+	if len(fifo.objects) > 0 {
+		object = fifo.objects[0]
+		fifo.objects = fifo.objects[1:]
+	}
+	lcdc := mem.io[0x40]
+	// LCDC bit 0 blanks the background and the window on DMG
+	if !hasBit(uint16(lcdc), 0) {
+		background.color = 0
+	}
+	// palettes are applied when pixels leave the FIFO
+	shade := applyPalette(background.color, mem.io[0x47])
+	if object.color != 0 && hasBit(uint16(lcdc), 1) && (!object.priority || background.color == 0) {
+		palette := mem.io[0x48]
+		if object.palette {
+			palette = mem.io[0x49]
+		}
+		shade = applyPalette(object.color, palette)
+	}
+	gpu.background_line[fifo.x] = background.color
+	gpu.frame_buffer[fifo.x][gpu.line] = shade
+	fifo.x++
+}
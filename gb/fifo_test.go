@@ -0,0 +1,120 @@
+package gb
+
+import "testing"
+
+// Step the gpu until a frame has been rendered
+func stepGpuFrame(gpu *Gpu, mem *Memory) {
+	for i := 0; i < 70224; i++ {
+		gpu.step(4, mem)
+		if gpu.rendering {
+			return
+		}
+	}
+}
+
+// Step the pixel FIFO gpu to dot of line
+func stepFifoUntil(gpu *Gpu, mem *Memory, line int, dot int) {
+	for i := 0; i < 2*70224 && (gpu.line != line || gpu.fifo.dot != dot); i++ {
+		gpu.fifo.step(gpu, 1, mem)
+	}
+}
+
+// Length in dots of mode 3 on line
+func mode3Length(gpu *Gpu, mem *Memory, line int) int {
+	stepFifoUntil(gpu, mem, line, 80)
+	length := 0
+	for gpu.mode == 3 {
+		gpu.fifo.step(gpu, 1, mem)
+		length++
+	}
+	return length
+}
+
+func newFifoTest() (Gpu, Memory) {
+	var gpu Gpu
+	var mem Memory
+	gpu.fifo = &PixelFifo{}
+	gpu.mode = 2
+	writeBackgroundPattern(&mem, 0x9800, true)
+	mem.writeByte(0xff40, 0x93)
+	mem.writeByte(0xff47, 0xe4)
+	mem.writeByte(0xff48, 0xe4)
+	mem.writeByte(0xff49, 0x1b)
+	return gpu, mem
+}
+
+func TestFifoMatchesScanline(t *testing.T) {
+	fifo, mem := newFifoTest()
+	mem.writeByte(0xff40, 0xf3)
+	mem.writeByte(0xff42, 37)
+	mem.writeByte(0xff43, 133)
+	// window map at 0x9c00 from line 100, column 53
+	for i := 0; i < 0x400; i++ {
+		mem.vram[0x1c00+i] = byte(i * 7)
+	}
+	mem.writeByte(0xff4a, 100)
+	mem.writeByte(0xff4b, 60)
+	for index := 0; index < 40; index++ {
+		writeSprite(&mem, index, index*13%168-8, index*29%160-16, byte(index*5), byte(index*0x30))
+	}
+	var scanline Gpu
+	scanline.mode = 2
+	scanlineMem := mem
+	// the first frame starts in the middle of the display
+	for frame := 0; frame < 2; frame++ {
+		stepGpuFrame(&fifo, &mem)
+		stepGpuFrame(&scanline, &scanlineMem)
+	}
+	for y := 0; y < 144; y++ {
+		for x := 0; x < 160; x++ {
+			if fifo.frame_buffer[x][y] != scanline.frame_buffer[x][y] {
+				t.Fatalf("%d shade at (%d, %d), expected %d", fifo.frame_buffer[x][y], x, y, scanline.frame_buffer[x][y])
+			}
+		}
+	}
+}
+
+func TestFifoMode3Length(t *testing.T) {
+	gpu, mem := newFifoTest()
+	if length := mode3Length(&gpu, &mem, 1); length != 172 {
+		t.Errorf("%d dots in mode 3, expected 172", length)
+	}
+	// SCX fine scrolling discards pixels
+	mem.writeByte(0xff43, 5)
+	if length := mode3Length(&gpu, &mem, 2); length != 177 {
+		t.Errorf("%d dots in mode 3 with SCX=5, expected 177", length)
+	}
+	// the window restarts the fetcher
+	gpu, mem = newFifoTest()
+	mem.writeByte(0xff40, 0xb3)
+	mem.writeByte(0xff4b, 87)
+	if length := mode3Length(&gpu, &mem, 3); length != 178 {
+		t.Errorf("%d dots in mode 3 with the window, expected 178", length)
+	}
+	// a sprite aligned on a tile, fetched while the background fetcher is idle
+	gpu, mem = newFifoTest()
+	writeSprite(&mem, 0, 80, 0, 0, 0)
+	if length := mode3Length(&gpu, &mem, 4); length != 178 {
+		t.Errorf("%d dots in mode 3 with a sprite, expected 178", length)
+	}
+}
+
+func TestFifoMidScanlineWrite(t *testing.T) {
+	gpu, mem := newFifoTest()
+	stepFifoUntil(&gpu, &mem, 5, 80)
+	for gpu.fifo.x < 80 {
+		gpu.fifo.step(&gpu, 1, &mem)
+	}
+	// invert the palette for the right half of the line
+	mem.writeByte(0xff47, 0x1b)
+	stepFifoUntil(&gpu, &mem, 6, 0)
+	for x := 0; x < 160; x++ {
+		expected := int(backgroundPattern(x, 5))
+		if x >= 80 {
+			expected = 3 - expected
+		}
+		if gpu.frame_buffer[x][5] != expected {
+			t.Fatalf("%d shade at (%d, 5), expected %d", gpu.frame_buffer[x][5], x, expected)
+		}
+	}
+}
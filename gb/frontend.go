@@ -0,0 +1,25 @@
+package gb
+
+// Receives the frames completed by the emulator
+type VideoSink interface {
+	WriteFrame(frame Frame)
+}
+
+// Receives the audio samples produced by the emulator, interleaved left and right
+type AudioSink interface {
+	WriteSamples(samples []float32)
+}
+
+// Provides the joypad buttons pressed
+type InputSource interface {
+	Buttons() Buttons
+}
+
+// Connect the emulator to a frontend: RunFrame reads the buttons from input
+// before running, then writes the frame to video and the samples to audio.
+// Any of them can be nil.
+func (emu *Emulator) Connect(video VideoSink, audio AudioSink, input InputSource) {
+	emu.video = video
+	emu.audio = audio
+	emu.input = input
+}
@@ -0,0 +1,48 @@
+package gb
+
+import "testing"
+
+type testFrontend struct {
+	frames  int
+	samples int
+	buttons Buttons
+}
+
+func (frontend *testFrontend) WriteFrame(frame Frame) {
+	frontend.frames++
+}
+
+func (frontend *testFrontend) WriteSamples(samples []float32) {
+	frontend.samples += len(samples)
+}
+
+func (frontend *testFrontend) Buttons() Buttons {
+	return frontend.buttons
+}
+
+func TestConnect(t *testing.T) {
+	var frontend testFrontend
+	emu, err := New(makeRom(0x00, 0, 0), WithSampleRate(48000))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	emu.Connect(&frontend, &frontend, &frontend)
+	frontend.buttons = ButtonSelect
+	for i := 0; i < 3; i++ {
+		emu.RunFrame()
+	}
+	if frontend.frames != 3 {
+		t.Errorf("%d frames written, expected 3", frontend.frames)
+	}
+	if frontend.samples == 0 {
+		t.Errorf("no audio samples written")
+	}
+	if emu.buttons != ButtonSelect {
+		t.Errorf("buttons not read from the input source")
+	}
+	// sinks are optional
+	emu.Connect(nil, nil, &frontend)
+	if err := emu.RunFrame(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,215 @@
+package gb
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Frames run before comparing when a test ROM never reaches its LD B,B breakpoint
+const goldenMaxFrames int = 120
+
+// Directory of the diff images of the failed golden tests
+const goldenFailures string = "testdata/failures"
+
+// Run emu until it is about to execute LD B,B, the breakpoint of the test
+// ROMs, or for maxFrames frames. Returns true when the breakpoint was reached.
+func runUntilBreakpoint(emu *Emulator, maxFrames int) bool {
+	for frame := 0; frame < maxFrames; frame++ {
+		for {
+			if !emu.cpu.halted && !emu.cpu.stopped && emu.mem.readByte(emu.cpu.pc) == 0x40 {
+				return true
+			}
+			emu.StepInstruction()
+			if emu.gpu.rendering {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// Returns the shades of a reference image, by line, each pixel being mapped to the closest Palette color
+func readReference(path string) (Frame, error) {
+	var frame Frame
+	file, err := os.Open(path)
+	if err != nil {
+		return frame, err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return frame, err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != ScreenWidth || bounds.Dy() != ScreenHeight {
+		return frame, fmt.Errorf("%dx%d image, expected %dx%d", bounds.Dx(), bounds.Dy(), ScreenWidth, ScreenHeight)
+	}
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			frame[y][x] = byte(Palette.Index(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	return frame, nil
+}
+
+// Returns the number of pixels differing between frame and reference, and an
+// image of frame with the differing pixels in red
+func diffFrames(frame Frame, reference Frame) (int, *image.RGBA) {
+	diff := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+	count := 0
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			if frame[y][x] != reference[y][x] {
+				count++
+				diff.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+			} else {
+				// matching pixels are faded, so the differences stand out
+				gray := Palette[frame[y][x]&0x03].(color.Gray)
+				diff.Set(x, y, color.Gray{Y: 0xc0 + gray.Y/4})
+			}
+		}
+	}
+	return count, diff
+}
+
+// Write img to the PNG file at path
+func writePng(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Run the test ROM at romPath and compare its screen to the reference image
+// with the same name and the .png extension. The test is skipped when the ROM
+// is absent, and fails when the ROM is present without its reference image.
+// On failure, the frame and the diff image are written to goldenFailures.
+func runGoldenTest(t *testing.T, romPath string, opts ...Option) {
+	rom, err := os.ReadFile(romPath)
+	if os.IsNotExist(err) {
+		t.Skipf("%s not found", romPath)
+	} else if err != nil {
+		t.Fatalf("cannot read ROM: %s", err)
+	}
+	referencePath := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".png"
+	if _, err := os.Stat(referencePath); os.IsNotExist(err) {
+		t.Fatalf("reference image %s missing for %s, see testdata/README.md", referencePath, romPath)
+	}
+	reference, err := readReference(referencePath)
+	if err != nil {
+		t.Fatalf("cannot read reference image: %s", err)
+	}
+	emu, err := New(rom, opts...)
+	if err != nil {
+		t.Fatalf("cannot load ROM: %s", err)
+	}
+	if !runUntilBreakpoint(emu, goldenMaxFrames) {
+		t.Logf("LD B,B not reached after %d frames", goldenMaxFrames)
+	}
+	// the last complete frame, the breakpoint may be reached in the middle of the next one
+	frame := emu.FrameBuffer()
+	count, diff := diffFrames(frame, reference)
+	if count == 0 {
+		return
+	}
+	name := strings.ReplaceAll(t.Name(), "/", "_")
+	if err := os.MkdirAll(goldenFailures, 0755); err != nil {
+		t.Fatalf("cannot create the failures directory: %s", err)
+	}
+	diffPath := filepath.Join(goldenFailures, name+".diff.png")
+	actualPath := filepath.Join(goldenFailures, name+".png")
+	if err := writePng(diffPath, diff); err != nil {
+		t.Errorf("cannot write diff image: %s", err)
+	}
+	if err := writePng(actualPath, frame.Image()); err != nil {
+		t.Errorf("cannot write frame: %s", err)
+	}
+	t.Errorf("%d pixels differ from %s, see %s", count, referencePath, diffPath)
+}
+
+func TestGoldenDmgAcid2(t *testing.T) {
+	t.Run("scanline", func(t *testing.T) {
+		runGoldenTest(t, "testdata/dmg-acid2/dmg-acid2.gb")
+	})
+	t.Run("fifo", func(t *testing.T) {
+		runGoldenTest(t, "testdata/dmg-acid2/dmg-acid2.gb", WithPixelFifo())
+	})
+}
+
+// Mealybug tearoom tests change PPU registers mid-scanline, only the pixel FIFO renders them
+func TestGoldenMealybug(t *testing.T) {
+	roms, _ := filepath.Glob("testdata/mealybug/*.gb")
+	if len(roms) == 0 {
+		t.Skip("no ROM in testdata/mealybug")
+	}
+	for _, romPath := range roms {
+		romPath := romPath
+		t.Run(strings.TrimSuffix(filepath.Base(romPath), ".gb"), func(t *testing.T) {
+			runGoldenTest(t, romPath, WithPixelFifo())
+		})
+	}
+}
+
+func TestRunUntilBreakpoint(t *testing.T) {
+	rom := makeRom(0x00, 0, 0)
+	// NOP, NOP, LD B,B, JR -2
+	copy(rom[0x100:], []byte{0x00, 0x00, 0x40, 0x18, 0xfe})
+	emu, err := New(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !runUntilBreakpoint(emu, 1) {
+		t.Errorf("LD B,B breakpoint not reached")
+	}
+	if emu.cpu.pc != 0x102 {
+		t.Errorf("stopped at 0x%04X, expected 0x0102", emu.cpu.pc)
+	}
+
+	// JR -2
+	copy(rom[0x100:], []byte{0x18, 0xfe})
+	emu, err = New(rom)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if runUntilBreakpoint(emu, 2) {
+		t.Errorf("breakpoint reached without LD B,B")
+	}
+}
+
+func TestDiffFrames(t *testing.T) {
+	var frame Frame
+	frame[3][4] = 2
+	path := filepath.Join(t.TempDir(), "reference.png")
+	if err := writePng(path, frame.Image()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	reference, err := readReference(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if count, _ := diffFrames(frame, reference); count != 0 {
+		t.Errorf("%d pixels differ from the frame own image, expected 0", count)
+	}
+	frame[10][20] = 3
+	count, diff := diffFrames(frame, reference)
+	if count != 1 {
+		t.Errorf("%d pixels differ, expected 1", count)
+	}
+	if diff.RGBAAt(20, 10) != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("differing pixel not red in the diff image")
+	}
+	if diff.RGBAAt(4, 3) == (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("matching pixel red in the diff image")
+	}
+}
@@ -0,0 +1,361 @@
+package gb
+
+import "sort"
+
+type Gpu struct {
+	mode           int
+	mode_clock     int
+	line           int
+	frame_buffer   [160][144]int
+	rendering      bool
+	lcd            bool
+	scrollX        int
+	scrollY        int
+	sprite         bool
+	background     bool
+	sprite_size    bool
+	background_map bool
+	background_set bool
+	window         bool
+	window_map     bool
+	// LCDC bit 7 as last seen by step, the PPU restarts when it gets set
+	display bool
+	// color numbers of the background pixels of the current line, before palette
+	background_line [160]byte
+	// internal window line counter, only incremented on lines where the window is visible
+	window_line int
+	// set once LY matched WY in the current frame, the window can only appear after that
+	window_triggered bool
+	// STAT interrupt line, the interrupt is requested on its rising edge only
+	stat_line bool
+	// pixel FIFO renderer, the scanline renderer is used when nil
+	fifo *PixelFifo
+}
+
+const numtiles int = 512
+
+// Sprite attributes, as stored in OAM
+// see https://gbdev.io/pandocs/OAM.html
+type Sprite struct {
+	y     int
+	x     int
+	tile  byte
+	flags byte
+	index int
+}
+
+// Maximum number of sprites displayed on a single line
+const maxSpritesPerLine int = 10
+
+// Frame length in cycles, also kept while the LCD is off
+const frameCycles int = 70224
+
+// Advance the PPU by the given number of cycles
+func (gpu *Gpu) step(cycles int, mem *Memory) {
+	if !hasBit(uint16(mem.io[0x40]), 7) {
+		gpu.stepOff(cycles, mem)
+		return
+	}
+	if !gpu.display {
+		gpu.display = true
+		gpu.restart()
+	}
+	if gpu.fifo != nil {
+		gpu.fifo.step(gpu, cycles, mem)
+		return
+	}
+	gpu.mode_clock += cycles
+	gpu.rendering = false
+	switch gpu.mode {
+	// Hblank
+	case 0:
+		if gpu.mode_clock >= 204 {
+			gpu.mode_clock = 0
+			gpu.line++
+			if gpu.line == 144 {
+				// last vblank, render the framebuffer
+				gpu.mode = 1
+				gpu.rendering = true
+				mem.requestInterrupt(vblankInterrupt)
+			} else {
+				gpu.mode = 2
+			}
+		}
+	// Vblank
+	case 1:
+		if gpu.mode_clock >= 456 {
+			gpu.mode_clock = 0
+			gpu.line++
+			if gpu.line > 153 {
+				// Restart scanning
+				gpu.mode = 2
+				gpu.line = 0
+				gpu.window_line = 0
+				gpu.window_triggered = false
+			}
+		}
+	// Scanline (OAM access)
+	case 2:
+		if gpu.mode_clock >= 80 {
+			gpu.mode_clock = 0
+			gpu.mode = 3
+		}
+	// Scanline (VRAM access)
+	case 3:
+		if gpu.mode_clock >= 172 {
+			gpu.mode_clock = 0
+			gpu.mode = 0
+			//write scanline to frame buffer
+			gpu.setGpuControl(mem)
+			gpu.writeScanline(mem)
+			gpu.writeWindow(mem)
+			gpu.writeSprites(mem)
+		}
+	}
+	gpu.updateStat(mem)
+}
+
+// While the LCD is off LY and the STAT mode stay at 0, no interrupt is
+// requested and the screen is blank. Frames are still completed at the usual
+// rate so that the emulator keeps running.
+func (gpu *Gpu) stepOff(cycles int, mem *Memory) {
+	if gpu.display {
+		gpu.display = false
+		gpu.restart()
+		gpu.frame_buffer = [160][144]int{}
+	}
+	mem.io[0x44] = 0
+	mem.io[0x41] &^= 0x03
+	gpu.stat_line = false
+	gpu.rendering = false
+	gpu.mode_clock += cycles
+	if gpu.mode_clock >= frameCycles {
+		gpu.mode_clock -= frameCycles
+		gpu.rendering = true
+	}
+}
+
+// Start over at the beginning of line 0, as when the LCD is turned on
+func (gpu *Gpu) restart() {
+	gpu.line = 0
+	gpu.mode = 2
+	gpu.mode_clock = 0
+	gpu.window_line = 0
+	gpu.window_triggered = false
+	if gpu.fifo != nil {
+		gpu.fifo.dot = 0
+		gpu.fifo.window_drawn = false
+	}
+}
+
+// Update LY and the read-only STAT bits, and request the STAT interrupt on a
+// rising edge of the STAT interrupt line
+// see https://gbdev.io/pandocs/STAT.html
+func (gpu *Gpu) updateStat(mem *Memory) {
+	mem.io[0x44] = byte(gpu.line)
+	stat := mem.io[0x41] &^ 0x07
+	stat |= byte(gpu.mode)
+	coincidence := mem.io[0x44] == mem.io[0x45]
+	if coincidence {
+		stat |= 0x04
+	}
+	mem.io[0x41] = stat
+	line := (gpu.mode == 0 && hasBit(uint16(stat), 3)) ||
+		(gpu.mode == 1 && hasBit(uint16(stat), 4)) ||
+		(gpu.mode == 2 && hasBit(uint16(stat), 5)) ||
+		(coincidence && hasBit(uint16(stat), 6))
+	if line && !gpu.stat_line {
+		mem.requestInterrupt(lcdInterrupt)
+	}
+	gpu.stat_line = line
+}
+
+// Draw the background of the current line. Each pixel is fetched from the
+// 256x256 background map at (x + SCX, LY + SCY), wrapping around its edges.
+func (gpu *Gpu) writeScanline(mem *Memory) {
+	scrollY := int(mem.peekByte(0xff42))
+	scrollX := int(mem.peekByte(0xff43))
+	palette := mem.peekByte(0xff47)
+	// LCDC bit 0 blanks the background on DMG
+	if !gpu.lcd {
+		for x := 0; x < 160; x++ {
+			gpu.background_line[x] = 0
+			gpu.frame_buffer[x][gpu.line] = 0
+		}
+		return
+	}
+	// LCDC bit 3 selects the background map, bit 4 the tile data addressing
+	var map_region uint16 = 0x9800
+	if gpu.background_map {
+		map_region = 0x9C00
+	}
+
+	y := (gpu.line + scrollY) & 0xFF
+	map_line := uint16(y/8) * 32
+	tile_line := uint16(y%8) * 2
+
+	for pixel := 0; pixel < 160; pixel++ {
+		x := (pixel + scrollX) & 0xFF
+		tile_id := mem.peekByte(map_region + map_line + uint16(x/8))
+		address := tileDataAddress(tile_id, gpu.background_set) + tile_line
+		color := tileColor(mem.peekByte(address), mem.peekByte(address+1), 7-x%8)
+		gpu.background_line[pixel] = color
+		gpu.frame_buffer[pixel][gpu.line] = applyPalette(color, palette)
+	}
+}
+
+// Draw the window over the background of the current line
+func (gpu *Gpu) writeWindow(mem *Memory) {
+	wy := int(mem.peekByte(0xff4a))
+	wx := int(mem.peekByte(0xff4b))
+	if gpu.line == wy {
+		gpu.window_triggered = true
+	}
+	// LCDC bit 0 disables both background and window on DMG
+	if !gpu.window || !gpu.lcd || !gpu.window_triggered || wx > 166 {
+		return
+	}
+	var map_region uint16 = 0x9800
+	if gpu.window_map {
+		map_region = 0x9C00
+	}
+	map_line := uint16(gpu.window_line/8) * 32
+	tile_line := uint16(gpu.window_line%8) * 2
+	// the window starts at WX-7, with WX<7 its leftmost pixels are off screen
+	start := wx - 7
+	if start < 0 {
+		start = 0
+	}
+	for x := start; x < 160; x++ {
+		column := x - (wx - 7)
+		tile_id := mem.peekByte(map_region + map_line + uint16(column/8))
+		address := tileDataAddress(tile_id, gpu.background_set) + tile_line
+		color := tileColor(mem.peekByte(address), mem.peekByte(address+1), 7-column%8)
+		gpu.background_line[x] = color
+		gpu.frame_buffer[x][gpu.line] = applyPalette(color, mem.peekByte(0xff47))
+	}
+	gpu.window_line++
+}
+
+// Returns the address of tile tile_id in VRAM. With unsigned addressing tiles
+// 0-255 are at 0x8000-0x8FFF, otherwise tiles -128-127 are based at 0x9000.
+func tileDataAddress(tile_id byte, unsigned bool) uint16 {
+	if unsigned {
+		return 0x8000 + uint16(tile_id)*16
+	}
+	return uint16(0x9000 + int(int8(tile_id))*16)
+}
+
+// Returns the color number of pixel bit in a tile line stored as two bytes
+func tileColor(data_byte_1 byte, data_byte_2 byte, bit int) byte {
+	return (data_byte_1>>bit)&1 | ((data_byte_2>>bit)&1)<<1
+}
+
+// Returns the shade of color number color in palette
+func applyPalette(color byte, palette byte) int {
+	return int(palette>>(color*2)) & 0x03
+}
+
+// Returns the sprites on the current line in drawing priority order: on DMG the
+// sprite with the smallest X wins, then the one first in OAM. Only the first
+// 10 sprites found in OAM are displayed.
+func (gpu *Gpu) lineSprites(mem *Memory) []Sprite {
+	height := 8
+	if gpu.sprite_size {
+		height = 16
+	}
+	var sprites []Sprite
+	for index := 0; index < 40 && len(sprites) < maxSpritesPerLine; index++ {
+		sprite := Sprite{
+			y:     int(mem.oam[index*4]) - 16,
+			x:     int(mem.oam[index*4+1]) - 8,
+			tile:  mem.oam[index*4+2],
+			flags: mem.oam[index*4+3],
+			index: index,
+		}
+		if gpu.line >= sprite.y && gpu.line < sprite.y+height {
+			sprites = append(sprites, sprite)
+		}
+	}
+	sort.SliceStable(sprites, func(i, j int) bool {
+		return sprites[i].x < sprites[j].x
+	})
+	return sprites
+}
+
+// Draw the sprites of the current line over the background
+func (gpu *Gpu) writeSprites(mem *Memory) {
+	if !gpu.sprite || gpu.line >= 144 {
+		return
+	}
+	height := 8
+	if gpu.sprite_size {
+		height = 16
+	}
+	sprites := gpu.lineSprites(mem)
+	for x := 0; x < 160; x++ {
+		for _, sprite := range sprites {
+			column := x - sprite.x
+			if column < 0 || column >= 8 {
+				continue
+			}
+			row := gpu.line - sprite.y
+			// Y flip
+			if hasBit(uint16(sprite.flags), 6) {
+				row = height - 1 - row
+			}
+			tile := sprite.tile
+			if height == 16 {
+				tile = (tile & 0xFE) + byte(row/8)
+			}
+			address := 0x8000 + uint16(tile)*16 + uint16(row%8)*2
+			data_byte_1 := mem.peekByte(address)
+			data_byte_2 := mem.peekByte(address + 1)
+			bit := 7 - column
+			// X flip
+			if hasBit(uint16(sprite.flags), 5) {
+				bit = column
+			}
+			color := tileColor(data_byte_1, data_byte_2, bit)
+			// color 0 is transparent, the next sprite may be visible
+			if color == 0 {
+				continue
+			}
+			// background colors 1-3 are drawn over sprites with the priority flag
+			if !hasBit(uint16(sprite.flags), 7) || gpu.background_line[x] == 0 {
+				palette := mem.peekByte(0xff48)
+				if hasBit(uint16(sprite.flags), 4) {
+					palette = mem.peekByte(0xff49)
+				}
+				gpu.frame_buffer[x][gpu.line] = applyPalette(color, palette)
+			}
+			break
+		}
+	}
+}
+
+func (gpu *Gpu) getVram(mem *Memory) [numtiles * 8][8]byte {
+	var vram [numtiles * 8][8]byte
+	for byte_index := 0; byte_index < numtiles*8; byte_index++ {
+		for i := 1; i < 8; i++ {
+			var mask byte = 1 << i
+			pixel1 := mem.vram[byte_index*2]
+			pixel2 := mem.vram[byte_index*2+1]
+			a := (pixel1 & mask) >> i
+			b := (pixel2 & mask) >> i
+			vram[byte_index][i] = a + b
+		}
+	}
+	return vram
+}
+
+func (gpu *Gpu) setGpuControl(mem *Memory) {
+	gpuRegister := mem.peekByte(0xff40)
+	gpu.lcd = hasBit(uint16(gpuRegister), 0)
+	gpu.sprite = hasBit(uint16(gpuRegister), 1)
+	gpu.sprite_size = hasBit(uint16(gpuRegister), 2)
+	gpu.background_map = hasBit(uint16(gpuRegister), 3)
+	gpu.background_set = hasBit(uint16(gpuRegister), 4)
+	gpu.window = hasBit(uint16(gpuRegister), 5)
+	gpu.window_map = hasBit(uint16(gpuRegister), 6)
+}
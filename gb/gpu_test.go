@@ -0,0 +1,426 @@
+package gb
+
+import "testing"
+
+// Write a tile where every pixel of line row has color number color
+func writeTileLine(mem *Memory, tile int, row int, color byte) {
+	var data_byte_1, data_byte_2 byte
+	if color&1 != 0 {
+		data_byte_1 = 0xff
+	}
+	if color&2 != 0 {
+		data_byte_2 = 0xff
+	}
+	mem.vram[tile*16+row*2] = data_byte_1
+	mem.vram[tile*16+row*2+1] = data_byte_2
+}
+
+// Write sprite index in OAM, x and y being screen coordinates
+func writeSprite(mem *Memory, index int, x int, y int, tile byte, flags byte) {
+	mem.oam[index*4] = byte(y + 16)
+	mem.oam[index*4+1] = byte(x + 8)
+	mem.oam[index*4+2] = tile
+	mem.oam[index*4+3] = flags
+}
+
+func newSpriteTest(line int) (Gpu, Memory) {
+	var gpu Gpu
+	var mem Memory
+	gpu.line = line
+	gpu.sprite = true
+	// identity palettes: color number n is shade n
+	mem.writeByte(0xff48, 0xe4)
+	mem.writeByte(0xff49, 0xe4)
+	return gpu, mem
+}
+
+func TestSpriteFlip(t *testing.T) {
+	gpu, mem := newSpriteTest(10)
+	// tile 1: only the leftmost pixel of line 0 has color 3
+	mem.vram[16] = 0x80
+	mem.vram[17] = 0x80
+	writeSprite(&mem, 0, 20, 10, 1, 0x00)
+	writeSprite(&mem, 1, 40, 10, 1, 0x20)
+	writeSprite(&mem, 2, 60, 3, 1, 0x40)
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[20][10] != 3 || gpu.frame_buffer[21][10] != 0 {
+		t.Errorf("sprite not drawn at its position")
+	}
+	if gpu.frame_buffer[47][10] != 3 || gpu.frame_buffer[40][10] != 0 {
+		t.Errorf("sprite not flipped horizontally")
+	}
+	if gpu.frame_buffer[60][10] != 3 {
+		t.Errorf("sprite not flipped vertically")
+	}
+}
+
+func TestSpritePalette(t *testing.T) {
+	gpu, mem := newSpriteTest(0)
+	mem.writeByte(0xff48, 0x1b)
+	mem.writeByte(0xff49, 0x40)
+	writeTileLine(&mem, 1, 0, 1)
+	writeSprite(&mem, 0, 0, 0, 1, 0x00)
+	writeSprite(&mem, 1, 8, 0, 1, 0x10)
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[0][0] != 2 {
+		t.Errorf("%d shade with OBP0, expected 2", gpu.frame_buffer[0][0])
+	}
+	if gpu.frame_buffer[8][0] != 0 {
+		t.Errorf("%d shade with OBP1, expected 0", gpu.frame_buffer[8][0])
+	}
+}
+
+func TestSpriteBackgroundPriority(t *testing.T) {
+	gpu, mem := newSpriteTest(0)
+	writeTileLine(&mem, 1, 0, 2)
+	writeSprite(&mem, 0, 0, 0, 1, 0x80)
+	gpu.background_line[0] = 1
+	gpu.frame_buffer[0][0] = 1
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[0][0] != 1 {
+		t.Errorf("sprite drawn over background color 1")
+	}
+	if gpu.frame_buffer[1][0] != 2 {
+		t.Errorf("sprite not drawn over background color 0")
+	}
+}
+
+func TestSpriteTransparencyAndOrder(t *testing.T) {
+	gpu, mem := newSpriteTest(0)
+	// tile 1 is transparent on its left half, tile 2 is color 1
+	mem.vram[16] = 0x0f
+	mem.vram[17] = 0x0f
+	writeTileLine(&mem, 2, 0, 1)
+	// the sprite with the smallest X wins, regardless of OAM order
+	writeSprite(&mem, 0, 4, 0, 2, 0x00)
+	writeSprite(&mem, 1, 0, 0, 1, 0x00)
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[4][0] != 3 {
+		t.Errorf("sprite with the smallest X not drawn on top")
+	}
+	if gpu.frame_buffer[2][0] != 0 {
+		t.Errorf("transparent pixel drawn")
+	}
+	if gpu.frame_buffer[8][0] != 1 {
+		t.Errorf("sprite not visible through transparent pixels")
+	}
+}
+
+func TestSpriteLimit(t *testing.T) {
+	gpu, mem := newSpriteTest(0)
+	writeTileLine(&mem, 1, 0, 3)
+	for index := 0; index < 12; index++ {
+		writeSprite(&mem, index, index*8, 0, 1, 0x00)
+	}
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[72][0] != 3 {
+		t.Errorf("10th sprite of the line not drawn")
+	}
+	if gpu.frame_buffer[80][0] != 0 || gpu.frame_buffer[88][0] != 0 {
+		t.Errorf("more than 10 sprites drawn on a line")
+	}
+}
+
+func TestSpriteTall(t *testing.T) {
+	gpu, mem := newSpriteTest(12)
+	gpu.sprite_size = true
+	writeTileLine(&mem, 2, 3, 1)
+	writeTileLine(&mem, 3, 4, 2)
+	// in 8x16 mode the lowest bit of the tile number is ignored
+	writeSprite(&mem, 0, 0, 0, 3, 0x00)
+	writeSprite(&mem, 1, 8, 0, 3, 0x40)
+	gpu.writeSprites(&mem)
+	if gpu.frame_buffer[0][12] != 2 {
+		t.Errorf("%d shade, expected bottom tile", gpu.frame_buffer[0][12])
+	}
+	if gpu.frame_buffer[8][12] != 1 {
+		t.Errorf("%d shade, expected flipped top tile", gpu.frame_buffer[8][12])
+	}
+}
+
+func newWindowTest(wx byte, wy byte) (Gpu, Memory) {
+	var gpu Gpu
+	var mem Memory
+	gpu.lcd = true
+	gpu.window = true
+	gpu.background_set = true
+	mem.writeByte(0xff47, 0xe4)
+	mem.writeByte(0xff4a, wy)
+	mem.writeByte(0xff4b, wx)
+	// window map: tile 1 then tile 2 on the first row, tile 3 on the second row
+	mem.vram[0x1800] = 1
+	mem.vram[0x1801] = 2
+	mem.vram[0x1820] = 3
+	for row := 0; row < 8; row++ {
+		writeTileLine(&mem, 1, row, 1)
+		writeTileLine(&mem, 2, row, 2)
+		writeTileLine(&mem, 3, row, 3)
+	}
+	return gpu, mem
+}
+
+func TestWindowPosition(t *testing.T) {
+	gpu, mem := newWindowTest(7, 0)
+	gpu.writeWindow(&mem)
+	if gpu.frame_buffer[0][0] != 1 || gpu.frame_buffer[8][0] != 2 {
+		t.Errorf("window with WX=7 does not start at the left edge")
+	}
+	gpu, mem = newWindowTest(3, 0)
+	gpu.writeWindow(&mem)
+	if gpu.frame_buffer[3][0] != 1 || gpu.frame_buffer[4][0] != 2 {
+		t.Errorf("window with WX<7 not shifted off screen")
+	}
+	gpu, mem = newWindowTest(87, 0)
+	gpu.writeWindow(&mem)
+	if gpu.frame_buffer[79][0] != 0 || gpu.frame_buffer[80][0] != 1 {
+		t.Errorf("window with WX=87 does not start at x=80")
+	}
+	if gpu.background_line[80] != 1 {
+		t.Errorf("window color not recorded for sprite priority")
+	}
+}
+
+func TestWindowLineCounter(t *testing.T) {
+	gpu, mem := newWindowTest(7, 2)
+	for gpu.line = 0; gpu.line < 20; gpu.line++ {
+		// hide the window on lines 5 to 9
+		gpu.window = gpu.line < 5 || gpu.line >= 10
+		gpu.writeWindow(&mem)
+	}
+	if gpu.frame_buffer[0][1] != 0 {
+		t.Errorf("window drawn above WY")
+	}
+	if gpu.frame_buffer[0][2] != 1 {
+		t.Errorf("first window line not drawn at WY")
+	}
+	// lines 2-4 and 10-14 show window lines 0-7, line 15 shows window line 8
+	if gpu.frame_buffer[0][14] != 1 || gpu.frame_buffer[0][15] != 3 {
+		t.Errorf("window line counter advanced on lines without window")
+	}
+	if gpu.window_line != 13 {
+		t.Errorf("%d in window line counter, expected 13", gpu.window_line)
+	}
+}
+
+func TestTileDataAddress(t *testing.T) {
+	if address := tileDataAddress(0x80, true); address != 0x8800 {
+		t.Errorf("0x%04x address for unsigned tile 0x80, expected 0x8800", address)
+	}
+	if address := tileDataAddress(0x00, false); address != 0x9000 {
+		t.Errorf("0x%04x address for signed tile 0, expected 0x9000", address)
+	}
+	if address := tileDataAddress(0xff, false); address != 0x8ff0 {
+		t.Errorf("0x%04x address for signed tile -1, expected 0x8ff0", address)
+	}
+}
+
+// Step the gpu until it reaches line and mode
+func stepGpuUntil(gpu *Gpu, mem *Memory, line int, mode int) {
+	for i := 0; i < 70224 && (gpu.line != line || gpu.mode != mode); i++ {
+		gpu.step(4, mem)
+	}
+}
+
+func TestStatMode(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	gpu.mode = 2
+	mem.writeByte(0xff40, 0x80)
+	stepGpuUntil(&gpu, &mem, 10, 3)
+	if mem.readByte(0xff41) != 0x83 || mem.readByte(0xff44) != 10 {
+		t.Errorf("0x%02x in STAT and %d in LY, expected 0x83 and 10", mem.readByte(0xff41), mem.readByte(0xff44))
+	}
+	// mode and coincidence bits are read-only
+	mem.writeByte(0xff41, 0xff)
+	if mem.readByte(0xff41) != 0xfb {
+		t.Errorf("0x%02x in STAT, expected 0xfb", mem.readByte(0xff41))
+	}
+}
+
+func TestVblankInterrupt(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	gpu.mode = 2
+	mem.writeByte(0xff40, 0x80)
+	stepGpuUntil(&gpu, &mem, 143, 0)
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(vblankInterrupt)) {
+		t.Errorf("vblank interrupt requested before line 144")
+	}
+	stepGpuUntil(&gpu, &mem, 144, 1)
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(vblankInterrupt)) {
+		t.Errorf("vblank interrupt not requested at line 144")
+	}
+	stepGpuUntil(&gpu, &mem, 0, 2)
+	if mem.readByte(0xff44) != 0 {
+		t.Errorf("%d in LY after vblank, expected 0", mem.readByte(0xff44))
+	}
+}
+
+func TestLycInterrupt(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	gpu.mode = 2
+	mem.writeByte(0xff40, 0x80)
+	mem.writeByte(0xff45, 20)
+	mem.writeByte(0xff41, 0x40)
+	stepGpuUntil(&gpu, &mem, 20, 2)
+	if !hasBit(uint16(mem.readByte(0xff41)), 2) {
+		t.Errorf("coincidence flag not set")
+	}
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(lcdInterrupt)) {
+		t.Errorf("STAT interrupt not requested on LY=LYC")
+	}
+	stepGpuUntil(&gpu, &mem, 21, 2)
+	if hasBit(uint16(mem.readByte(0xff41)), 2) {
+		t.Errorf("coincidence flag still set")
+	}
+}
+
+func TestStatBlocking(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	gpu.mode = 2
+	mem.writeByte(0xff40, 0x80)
+	// hblank and LYC sources both enabled, with LYC matching the line
+	mem.writeByte(0xff45, 5)
+	mem.writeByte(0xff41, 0x48)
+	stepGpuUntil(&gpu, &mem, 5, 2)
+	mem.writeByte(0xff0f, 0)
+	// the line stays high from LY=LYC through hblank, no new interrupt
+	stepGpuUntil(&gpu, &mem, 5, 0)
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(lcdInterrupt)) {
+		t.Errorf("STAT interrupt requested while the STAT line was already high")
+	}
+	// on the next line the hblank source triggers again after mode 2 and 3
+	stepGpuUntil(&gpu, &mem, 6, 0)
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(lcdInterrupt)) {
+		t.Errorf("STAT interrupt not requested on hblank")
+	}
+}
+
+func TestLcdOff(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	gpu.mode = 2
+	mem.writeByte(0xff40, 0x80)
+	mem.writeByte(0xff41, 0x78)
+	stepGpuUntil(&gpu, &mem, 50, 3)
+	mem.writeByte(0xff40, 0x00)
+	mem.writeByte(0xff0f, 0)
+	for i := 0; i < 70224; i++ {
+		gpu.step(4, &mem)
+		if mem.readByte(0xff44) != 0 || mem.readByte(0xff41)&0x03 != 0 {
+			t.Fatalf("%d in LY and mode %d with the LCD off, expected 0 and 0", mem.readByte(0xff44), mem.readByte(0xff41)&0x03)
+		}
+	}
+	if mem.readByte(0xff0f)&0x1f != 0 {
+		t.Errorf("0x%02x in IF with the LCD off, expected no interrupt", mem.readByte(0xff0f))
+	}
+	// frames are still completed while the LCD is off
+	rendered := false
+	for i := 0; i < 70224/4 && !rendered; i++ {
+		gpu.step(4, &mem)
+		rendered = gpu.rendering
+	}
+	if !rendered {
+		t.Errorf("no frame completed with the LCD off")
+	}
+	// turning the LCD back on restarts at the beginning of line 0
+	mem.writeByte(0xff40, 0x80)
+	gpu.step(4, &mem)
+	if gpu.line != 0 || gpu.mode != 2 || gpu.mode_clock != 4 {
+		t.Errorf("line %d, mode %d and %d cycles after turning the LCD on, expected line 0, mode 2 and 4 cycles",
+			gpu.line, gpu.mode, gpu.mode_clock)
+	}
+}
+
+// Color number of the test pattern at (x, y) in the background map
+func backgroundPattern(x int, y int) byte {
+	tile := (x/8 + y/8*3) & 0xff
+	return byte((x%8 + y%8 + tile) % 4)
+}
+
+// Fill the background map at map_region and the tile data so that the map
+// shows backgroundPattern
+func writeBackgroundPattern(mem *Memory, map_region uint16, unsigned bool) {
+	for row := 0; row < 32; row++ {
+		for col := 0; col < 32; col++ {
+			mem.writeByte(map_region+uint16(row*32+col), byte(col+row*3))
+		}
+	}
+	for tile := 0; tile < 256; tile++ {
+		address := tileDataAddress(byte(tile), unsigned)
+		for row := 0; row < 8; row++ {
+			var data_byte_1, data_byte_2 byte
+			for col := 0; col < 8; col++ {
+				color := byte((col + row + tile) % 4)
+				data_byte_1 |= (color & 1) << (7 - col)
+				data_byte_2 |= (color >> 1) << (7 - col)
+			}
+			mem.writeByte(address+uint16(row*2), data_byte_1)
+			mem.writeByte(address+uint16(row*2)+1, data_byte_2)
+		}
+	}
+}
+
+// Render the background of a whole frame
+func renderBackground(gpu *Gpu, mem Memory) {
+	gpu.setGpuControl(&mem)
+	for gpu.line = 0; gpu.line < 144; gpu.line++ {
+		gpu.writeScanline(&mem)
+	}
+}
+
+func TestBackgroundGoldenFrame(t *testing.T) {
+	for _, test := range []struct {
+		lcdc    byte
+		scrollX int
+		scrollY int
+	}{
+		{0x91, 0, 0},
+		{0x91, 3, 5},
+		// scrolling wraps around the 256x256 map
+		{0x91, 200, 250},
+		// 0x9C00 map, signed tile data addressing
+		{0x89, 117, 131},
+	} {
+		var gpu Gpu
+		var mem Memory
+		map_region := uint16(0x9800)
+		if hasBit(uint16(test.lcdc), 3) {
+			map_region = 0x9c00
+		}
+		writeBackgroundPattern(&mem, map_region, hasBit(uint16(test.lcdc), 4))
+		mem.writeByte(0xff40, test.lcdc)
+		mem.writeByte(0xff42, byte(test.scrollY))
+		mem.writeByte(0xff43, byte(test.scrollX))
+		// shades are the reverse of color numbers
+		mem.writeByte(0xff47, 0x1b)
+		renderBackground(&gpu, mem)
+		for y := 0; y < 144; y++ {
+			for x := 0; x < 160; x++ {
+				expected := 3 - int(backgroundPattern((x+test.scrollX)&0xff, (y+test.scrollY)&0xff))
+				if gpu.frame_buffer[x][y] != expected {
+					t.Fatalf("LCDC 0x%02x SCX %d SCY %d: %d shade at (%d, %d), expected %d",
+						test.lcdc, test.scrollX, test.scrollY, gpu.frame_buffer[x][y], x, y, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestBackgroundDisabled(t *testing.T) {
+	var gpu Gpu
+	var mem Memory
+	writeBackgroundPattern(&mem, 0x9800, true)
+	mem.writeByte(0xff40, 0x90)
+	mem.writeByte(0xff47, 0x1b)
+	renderBackground(&gpu, mem)
+	for y := 0; y < 144; y++ {
+		for x := 0; x < 160; x++ {
+			if gpu.frame_buffer[x][y] != 0 || gpu.background_line[x] != 0 {
+				t.Fatalf("background drawn at (%d, %d) with LCDC bit 0 reset", x, y)
+			}
+		}
+	}
+}
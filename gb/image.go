@@ -0,0 +1,25 @@
+package gb
+
+import (
+	"image"
+	"image/color"
+)
+
+// Colors of the shades 0 (white) to 3 (black) of the DMG screen
+var Palette = color.Palette{
+	color.Gray{Y: 0xff},
+	color.Gray{Y: 0xaa},
+	color.Gray{Y: 0x55},
+	color.Gray{Y: 0x00},
+}
+
+// Returns the frame as a 160x144 image colored with Palette
+func (frame *Frame) Image() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, ScreenWidth, ScreenHeight), Palette)
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			img.SetColorIndex(x, y, frame[y][x]&0x03)
+		}
+	}
+	return img
+}
@@ -0,0 +1,30 @@
+package gb
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestFrameImage(t *testing.T) {
+	var frame Frame
+	frame[0][0] = 3
+	frame[143][159] = 1
+	frame[10][20] = 2
+	img := frame.Image()
+	if img.Bounds().Dx() != ScreenWidth || img.Bounds().Dy() != ScreenHeight {
+		t.Fatalf("%v image bounds, expected 160x144", img.Bounds())
+	}
+	for _, pixel := range []struct {
+		x, y  int
+		color color.Gray
+	}{
+		{0, 0, color.Gray{Y: 0x00}},
+		{159, 143, color.Gray{Y: 0xaa}},
+		{20, 10, color.Gray{Y: 0x55}},
+		{1, 0, color.Gray{Y: 0xff}},
+	} {
+		if c := img.At(pixel.x, pixel.y); c != pixel.color {
+			t.Errorf("%v at %d,%d, expected %v", c, pixel.x, pixel.y, pixel.color)
+		}
+	}
+}
@@ -0,0 +1,1113 @@
+package gb
+
+import "testing"
+
+func TestLdnnn(t *testing.T) {
+	var reg Register
+	dest := "B"
+	var value byte = 10
+	reg.ldnnn(value, dest)
+	if reg.b != value {
+		t.Errorf("%d for register A, expected %d", reg.a, value)
+	}
+}
+
+func TestLdr1r2(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	mem.writeByte(uint16(reg.a), 10)
+	reg.ldr1r2("D", "A", &mem)
+	if reg.d != reg.a {
+		t.Errorf("%d for register D, expected %d", reg.a, reg.d)
+	}
+}
+
+func TestLdAn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	mem.writeByte(10, 10)
+	reg.c = 10
+	reg.ldAn("C", &mem)
+	if reg.a != mem.readByte(uint16(reg.c)) {
+		t.Errorf("%d for register A, expected %d", reg.a, mem.readByte(uint16(reg.c)))
+	}
+}
+
+func TestLdnA(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	reg.c = 20
+	reg.ldnA("C", &mem)
+	if mem.readByte(20) != reg.a {
+		t.Errorf("%d for memory at adress 20, expected %d", mem.readByte(20), reg.a)
+	}
+}
+func TestLdAC(t *testing.T) {
+	var reg Register
+	var value byte = 10
+	var mem Memory
+	mem.io[0x7f] = value
+	reg.c = 0x7f
+	reg.ldAC(&mem)
+	if reg.a != value {
+		t.Errorf("%d for register A, expected %d", reg.a, value)
+	}
+}
+
+func TestLdCA(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	reg.c = 0x7f
+	reg.ldCA(&mem)
+	if mem.io[0x7f] != reg.a {
+		t.Errorf("%d for io memory at adress register C, expected %d", mem.io[0x7f], reg.a)
+	}
+}
+
+func TestLddAHL(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.h = 2
+	reg.l = 1
+	mem.writeByte(513, 10)
+	reg.lddAHL(&mem)
+	// HL is 000001000000001 in binary (513 in decimal)
+	if mem.readByte(513) != reg.a {
+		t.Errorf("%d in register A, expected %d", reg.a, mem.readByte(513))
+	}
+	if reg.l != 0 {
+		t.Errorf("%d in register L, expected 0", reg.l)
+	}
+	if reg.h != 2 {
+		t.Errorf("%d in register H, expected 2", reg.l)
+	}
+}
+
+func TestLddHLA(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	reg.h = 2
+	reg.l = 1
+	reg.lddAHL(&mem)
+	// HL is 000001000000001 in binary (513 in decimal)
+	if mem.readByte(513) != reg.a {
+		t.Errorf("%d at memory at adress HL, expected %d", mem.readByte(513), reg.a)
+	}
+	if reg.l != 0 {
+		t.Errorf("%d in register L, expected 0", reg.l)
+	}
+	if reg.h != 2 {
+		t.Errorf("%d in register H, expected 2", reg.l)
+	}
+}
+
+func TestLdiAHL(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.h = 2
+	reg.l = 1
+	mem.writeByte(513, 10)
+	reg.ldiAHL(&mem)
+	// HL is 000001000000001 in binary (513 in decimal)
+	if mem.readByte(513) != reg.a {
+		t.Errorf("%d in register A, expected %d", reg.a, mem.readByte(513))
+	}
+	if reg.l != 2 {
+		t.Errorf("%d in register L, expected 2", reg.l)
+	}
+	if reg.h != 2 {
+		t.Errorf("%d in register H, expected 2", reg.l)
+	}
+}
+
+func TestLdiHLA(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	reg.h = 2
+	reg.l = 1
+	reg.ldiHLA(&mem)
+	// HL is 000001000000001 in binary (513 in decimal)
+	if mem.readByte(513) != reg.a {
+		t.Errorf("%d at memory at adress HL, expected %d", mem.readByte(513), reg.a)
+	}
+	if reg.l != 2 {
+		t.Errorf("%d in register L, expected 0", reg.l)
+	}
+	if reg.h != 2 {
+		t.Errorf("%d in register H, expected 2", reg.l)
+	}
+}
+
+func TestLdhnA(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 10
+	reg.ldhnA(1, &mem)
+	var address uint16 = 0xFF00 + 0x0001
+	if mem.readByte(address) != reg.a {
+		t.Errorf("%d value in memory address 0xFF01, expected register A", reg.l)
+	}
+}
+
+func TestLdhAn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	var address uint16 = 0xFF00 + 0x0001
+	mem.writeByte(address, 10)
+	reg.ldhnA(1, &mem)
+	if mem.readByte(address) != reg.a {
+		t.Errorf("%d value in memory address 0xFF01, expected register A", reg.l)
+	}
+}
+
+func TestLdnnn16(t *testing.T) {
+	var reg Register
+	var value uint16 = 0x0102
+	reg.ldnnn16(value, "BC")
+	if reg.b != 1 {
+		t.Errorf("%d value in register B, expected 1", reg.b)
+	}
+	if reg.c != 2 {
+		t.Errorf("%d value in register C, expected 2", reg.b)
+	}
+}
+
+func TestLdSPHL(t *testing.T) {
+	var reg Register
+	reg.h = 1
+	reg.l = 2
+	var value uint16 = 0x0102
+	reg.ldSPHL()
+	if reg.sp != value {
+		t.Errorf("%d value in register sp, expected %d", reg.b, value)
+	}
+}
+
+func TestLdHLSPn(t *testing.T) {
+	var reg Register
+	reg.sp = 0x0102
+	reg.ldHLSPn(1)
+	if reg.h != 1 {
+		t.Errorf("%d in register h, expected 1", reg.h)
+	}
+	if reg.l != 3 {
+		t.Errorf("%d in register l, expected 3", reg.l)
+	}
+	// -1 sets the carry and half carry flags of the low byte addition
+	reg.sp = 0x0102
+	reg.ldHLSPn(0xff)
+	if reg.getHLregister() != 0x0101 || reg.sp != 0x0102 {
+		t.Errorf("0x%04x in HL, expected 0x0101", reg.getHLregister())
+	}
+	if reg.flags != 0x30 {
+		t.Errorf("0x%02x in flags, expected 0x30", reg.flags)
+	}
+}
+
+func TestLdnnSP(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.sp = 10
+	reg.ldnnSP(10, &mem)
+	if mem.readWord(10) != reg.sp {
+		t.Errorf("%d at memory address 10, expected %d (sp)", mem.readWord(10), reg.sp)
+	}
+}
+
+func TestPushnn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.b = 1
+	reg.c = 1
+	reg.sp = 10
+	reg.pushnn("BC", &mem)
+	if mem.readByte(8) != reg.c {
+		t.Errorf("%d at memory address 8, expected %d", mem.readByte(8), reg.c)
+	}
+	if mem.readByte(9) != reg.b {
+		t.Errorf("%d at memory address 9, expected %d", mem.readByte(9), reg.b)
+	}
+	if reg.sp != 8 {
+		t.Errorf("%d in sp, expected 8", reg.sp)
+	}
+}
+
+func TestPopnn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.sp = 10
+	mem.rom[10] = 1
+	mem.rom[11] = 2
+	reg.popnn("BC", &mem)
+	if reg.c != 1 {
+		t.Errorf("%d at register C, expected 1", reg.c)
+	}
+	if reg.b != 2 {
+		t.Errorf("%d at register B, expected 2", reg.b)
+	}
+	if reg.sp != 12 {
+		t.Errorf("%d in sp, expected 12", reg.sp)
+	}
+}
+
+func TestAddAn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.addAn(value)
+	if reg.a != value {
+		t.Errorf("%d in register a, expected %d", reg.a, value)
+	}
+}
+
+func TestAddcAn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.flags = 1 << 4
+	reg.addcAn(value)
+	if reg.a != (value + 1) {
+		t.Errorf("%d in register a, expected %d", reg.a, value+1)
+	}
+}
+
+func TestSubn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.a = 1
+	reg.subn(value)
+	if reg.a != 0 {
+		t.Errorf("%d in register a, expected 0", reg.a)
+	}
+}
+
+func TestSbcAn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.flags = 1 << 4
+	reg.a = 1
+	reg.sbcAn(value)
+	if reg.a != 1 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestAndn(t *testing.T) {
+	var value byte = 0
+	var reg Register
+	reg.a = 1
+	reg.andn(value)
+	if reg.a != 0 {
+		t.Errorf("%d in register A, expected 0", reg.a)
+	}
+}
+
+func TestOrn(t *testing.T) {
+	var value byte = 0
+	var reg Register
+	reg.a = 1
+	reg.orn(value)
+	if reg.a != 1 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestXorn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.a = 1
+	reg.xorn(value)
+	if reg.a != 0 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestCpn(t *testing.T) {
+	var value byte = 1
+	var reg Register
+	reg.a = 1
+	reg.cpn(value)
+	if !hasBit(uint16(reg.flags), 7) {
+		t.Errorf("expected zero flag to be 1, is 0")
+	}
+	value++
+	reg.cpn(value)
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("expected carry flag to be 1, is 0")
+	}
+}
+
+func TestIncn(t *testing.T) {
+	var reg Register
+	reg.incn("A")
+	if reg.a != 1 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestDecn(t *testing.T) {
+	var reg Register
+	reg.a = 1
+	reg.decn("A")
+	if reg.a != 0 {
+		t.Errorf("%d in register A, expected 0", reg.a)
+	}
+}
+
+func TestAddHLn(t *testing.T) {
+	var reg Register
+	var value uint16 = 0x0102
+	reg.addHLn(value)
+	if reg.h != 1 {
+		t.Errorf("%d in register H, expected 1", reg.h)
+	}
+	if reg.l != 2 {
+		t.Errorf("%d in register L, expected 2", reg.l)
+	}
+}
+
+func TestAddSPn(t *testing.T) {
+	var reg Register
+	reg.sp = 0x0102
+	reg.addSPn(0x10)
+	if reg.sp != 0x0112 {
+		t.Errorf("0x%04x in stack pointer, expected 0x0112", reg.sp)
+	}
+	// -2: carry and half carry come from the low byte addition
+	reg.sp = 0x0100
+	reg.addSPn(0xfe)
+	if reg.sp != 0x00fe {
+		t.Errorf("0x%04x in stack pointer, expected 0x00fe", reg.sp)
+	}
+	if reg.flags != 0x00 {
+		t.Errorf("0x%02x in flags, expected 0x00", reg.flags)
+	}
+	reg.sp = 0x00ff
+	reg.addSPn(0xff)
+	if reg.sp != 0x00fe || reg.flags != 0x30 {
+		t.Errorf("0x%04x in stack pointer and 0x%02x in flags, expected 0x00fe and 0x30", reg.sp, reg.flags)
+	}
+}
+
+func TestAddSPnOpcode(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.pc = 0xc000
+	reg.sp = 0xdff0
+	// ADD SP,-16; NOP
+	mem.writeByte(0xc001, 0xf0)
+	reg.execute(0xe8, &mem)
+	if reg.sp != 0xdfe0 || reg.pc != 0xc002 {
+		t.Errorf("0x%04x in stack pointer and 0x%04x in program counter, expected 0xdfe0 and 0xc002", reg.sp, reg.pc)
+	}
+}
+
+func TestIncnn(t *testing.T) {
+	var reg Register
+	reg.b = 1
+	reg.c = 1
+	reg.incnn("BC")
+	if reg.b != 1 {
+		t.Errorf("%d in register b, expected 1", reg.b)
+	}
+	if reg.c != 2 {
+		t.Errorf("%d in register c, expected 2", reg.c)
+	}
+}
+
+func TestDecnn(t *testing.T) {
+	var reg Register
+	reg.b = 1
+	reg.c = 1
+	reg.decnn("BC")
+	if reg.b != 1 {
+		t.Errorf("%d in register b, expected 1", reg.b)
+	}
+	if reg.c != 0 {
+		t.Errorf("%d in register c, expected 0", reg.c)
+	}
+}
+
+func TestSwapn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 1
+	reg.swapn("A", &mem)
+	if reg.a != 0x10 {
+		t.Errorf("%d in register A, expected 128", reg.a)
+	}
+}
+
+func TestDaa(t *testing.T) {
+	var reg Register
+	reg.a = 1
+	reg.setRegisterFlag(true, 5)
+	reg.dAA()
+	if reg.a != 7 {
+		t.Errorf("%d in register A, expected 97", reg.a)
+	}
+}
+
+func TestCpl(t *testing.T) {
+	var reg Register
+	reg.a = 1
+	reg.cpl()
+	if reg.a != 254 {
+		t.Errorf("%d in register A, expexted 254", reg.a)
+	}
+}
+
+func TestCcf(t *testing.T) {
+	var reg Register
+	reg.setRegisterFlag(true, 4)
+	reg.ccf()
+	if reg.flags != 0 {
+		t.Errorf("failed to reset carry flag")
+	}
+}
+
+func TestScf(t *testing.T) {
+	var reg Register
+	reg.scf()
+	if reg.flags != 16 {
+		t.Errorf("failed to set carry flag")
+	}
+}
+
+func TestRlcA(t *testing.T) {
+	var reg Register
+	reg.a = 129
+	reg.rlcA()
+	if reg.a != 2 {
+		t.Errorf("%d in register A, expected 2", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("bit 7 of register A was not carried")
+	}
+}
+
+func TestRlA(t *testing.T) {
+	var reg Register
+	reg.a = 129
+	reg.setRegisterFlag(true, 4)
+	reg.rlA()
+	if reg.a != 3 {
+		t.Errorf("%d in register A, expected 3", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("bit 7 of register A was not carried")
+	}
+}
+
+func TestRrcA(t *testing.T) {
+	var reg Register
+	reg.a = 129
+	reg.rrcA()
+	if reg.a != 64 {
+		t.Errorf("%d in register A, expected 64", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("carry flag not set")
+	}
+}
+
+func TestRrA(t *testing.T) {
+	var reg Register
+	reg.a = 129
+	reg.setRegisterFlag(true, 4)
+	reg.rrA()
+	if reg.a != 192 {
+		t.Errorf("%d in register A, expected 64", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("carry flag not set")
+	}
+}
+
+func TestRlcn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.b = 129
+	reg.rlcn("B", &mem)
+	if reg.b != 3 {
+		t.Errorf("%d in register B, expected 3", reg.b)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("bit 7 of register A was not carried")
+	}
+}
+
+func TestRln(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.b = 129
+	reg.setRegisterFlag(true, 4)
+	reg.rln("B", &mem)
+	if reg.b != 3 {
+		t.Errorf("%d in register A, expected 3", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("bit 7 of register A was not carried")
+	}
+}
+
+func TestRrcn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.b = 129
+	reg.rrcn("B", &mem)
+	if reg.b != 192 {
+		t.Errorf("%d in register B, expected 192", reg.b)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("carry flag not set")
+	}
+}
+
+func TestRrn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.b = 129
+	reg.setRegisterFlag(true, 4)
+	reg.rrn("B", &mem)
+	if reg.b != 192 {
+		t.Errorf("%d in register A, expected 64", reg.a)
+	}
+	if !hasBit(uint16(reg.flags), 4) {
+		t.Errorf("carry flag not set")
+	}
+}
+
+func TestSlAn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 1
+	reg.slan("A", &mem)
+	if reg.a != 2 {
+		t.Errorf("%d in register A, expected 2", reg.a)
+	}
+}
+
+func TestSrAn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 130
+	reg.sran("A", &mem)
+	if reg.a != 193 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestSrln(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 2
+	reg.srln("A", &mem)
+	if reg.a != 1 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestBitBr(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 2
+	reg.bitBr("A", 0, &mem)
+	if !hasBit(uint16(reg.flags), 7) {
+		t.Errorf("zero flag not set")
+	}
+	reg.bitBr("A", 1, &mem)
+	if hasBit(uint16(reg.flags), 7) {
+		t.Errorf("zero flag set")
+	}
+}
+
+func TestSetBr(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.setBr("A", 0, &mem)
+	if reg.a != 1 {
+		t.Errorf("%d in register A, expected 1", reg.a)
+	}
+}
+
+func TestResBr(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.a = 1
+	reg.resBr("A", 0, &mem)
+	if reg.a != 0 {
+		t.Errorf("%d in register A, expected 0", reg.a)
+	}
+}
+
+func TestJpnn(t *testing.T) {
+	var reg Register
+	var value uint16 = 10
+	reg.jpnn(value)
+	if reg.pc != value {
+		t.Errorf("%d in program counter, expected %d", reg.pc, value)
+	}
+}
+
+func TestJpccnn(t *testing.T) {
+	var reg Register
+	var value uint16 = 10
+	reg.setRegisterFlag(false, 7)
+	reg.jpccnn(value, "Z")
+	if reg.pc != 0 {
+		t.Errorf("%d in program counter, expected 0", reg.pc)
+	}
+}
+
+func TestJpHL(t *testing.T) {
+	var reg Register
+	reg.h = 1
+	reg.l = 1
+	reg.jpHL()
+	if reg.pc != concatenateBytes(reg.h, reg.l) {
+		t.Errorf("%d in program counter, expected %d", reg.pc, concatenateBytes(reg.h, reg.l))
+	}
+}
+
+func TestJrn(t *testing.T) {
+	var reg Register
+	reg.pc = 1
+	reg.jrn(10)
+	if reg.pc != 11 {
+		t.Errorf("%d in program counter, expected 11", reg.pc)
+	}
+}
+
+func TestJrccnn(t *testing.T) {
+	var reg Register
+	reg.pc = 1
+	reg.setRegisterFlag(false, 7)
+	reg.jrccn(10, "Z")
+	if reg.pc != 1 {
+		t.Errorf("%d in program counter, expected 1", reg.pc)
+	}
+}
+
+func TestCallnn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	var value uint16 = 10
+	reg.pc = 0x0150
+	reg.sp = 0xdffe
+	reg.callnn(value, &mem)
+	if reg.pc != value {
+		t.Errorf("%d in program counter, expected %d", reg.pc, value)
+	}
+	if reg.sp != 0xdffc {
+		t.Errorf("0x%04x in stack pointer, expected 0xdffc", reg.sp)
+	}
+	if mem.readWord(reg.sp) != 0x0152 {
+		t.Errorf("adress not pushed to stack")
+	}
+}
+
+func TestCallccnn(t *testing.T) {
+	var reg Register
+	var mem Memory
+	var value uint16 = 10
+	reg.sp = 0xdffe
+	reg.callccnn(value, "Z", &mem)
+	if reg.pc == value {
+		t.Errorf("%d in program counter, call not expected", reg.pc)
+	}
+	if reg.sp != 0xdffe {
+		t.Errorf("stack pointer changed without call")
+	}
+}
+
+func TestEi(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.execute(0xfb, &mem)
+	if reg.ime {
+		t.Errorf("interrupts enabled right after EI, expected one instruction delay")
+	}
+	reg.execute(0x00, &mem)
+	if !reg.ime {
+		t.Errorf("interrupts not enabled after the instruction following EI")
+	}
+}
+
+func TestDi(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.execute(0xfb, &mem)
+	reg.execute(0xf3, &mem)
+	if reg.ime {
+		t.Errorf("interrupts enabled after EI followed by DI")
+	}
+}
+
+func TestReti(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.sp = 0xdffc
+	mem.writeWord(0xdffc, 0x1234)
+	reg.execute(0xd9, &mem)
+	if reg.pc != 0x1234 {
+		t.Errorf("%d in program counter, expected %d", reg.pc, 0x1234)
+	}
+	if !reg.ime {
+		t.Errorf("interrupts not enabled by RETI")
+	}
+}
+
+func TestHandleInterrupts(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.ime = true
+	reg.pc = 0x0150
+	reg.sp = 0xdffe
+	mem.writeByte(0xffff, 0x05)
+	mem.requestInterrupt(timerInterrupt)
+	mem.requestInterrupt(vblankInterrupt)
+	if !reg.handleInterrupts(&mem) {
+		t.Errorf("pending interrupt not serviced")
+	}
+	if reg.pc != 0x40 {
+		t.Errorf("%d in program counter, expected vblank vector %d", reg.pc, 0x40)
+	}
+	if reg.clock != 20 {
+		t.Errorf("%d clock cycles, expected 20", reg.clock)
+	}
+	if reg.ime {
+		t.Errorf("interrupts still enabled after dispatch")
+	}
+	if mem.readByte(0xff0f) != 0xe4 {
+		t.Errorf("%d in IF register, expected %d", mem.readByte(0xff0f), 0xe4)
+	}
+	if reg.sp != 0xdffc || mem.readWord(reg.sp) != 0x0150 {
+		t.Errorf("return address not pushed to stack")
+	}
+}
+
+func TestHandleInterruptsDisabled(t *testing.T) {
+	var reg Register
+	var mem Memory
+	mem.writeByte(0xffff, 0x01)
+	mem.requestInterrupt(vblankInterrupt)
+	if reg.handleInterrupts(&mem) {
+		t.Errorf("interrupt serviced while IME is reset")
+	}
+}
+
+func TestHaltWakeWithIme(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.ime = true
+	reg.sp = 0xdffe
+	mem.writeByte(0xffff, 0x01)
+	reg.execute(0x76, &mem)
+	if !reg.halted {
+		t.Errorf("CPU not halted")
+	}
+	reg.step(&mem)
+	if !reg.halted || reg.clock != 4 {
+		t.Errorf("CPU woke up without pending interrupt")
+	}
+	mem.requestInterrupt(vblankInterrupt)
+	reg.step(&mem)
+	if reg.halted {
+		t.Errorf("CPU still halted with pending interrupt")
+	}
+	if reg.pc != 0x40 {
+		t.Errorf("%d in program counter, expected vblank vector %d", reg.pc, 0x40)
+	}
+}
+
+func TestHaltWakeWithoutIme(t *testing.T) {
+	var reg Register
+	var mem Memory
+	mem.writeByte(0xffff, 0x04)
+	reg.execute(0x76, &mem)
+	if !reg.halted {
+		t.Errorf("CPU not halted")
+	}
+	mem.requestInterrupt(timerInterrupt)
+	reg.step(&mem)
+	if reg.halted {
+		t.Errorf("CPU still halted with pending interrupt")
+	}
+	if reg.pc != 1 {
+		t.Errorf("%d in program counter, expected 1", reg.pc)
+	}
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(timerInterrupt)) {
+		t.Errorf("interrupt serviced while IME is reset")
+	}
+}
+
+func TestHaltIgnoresDisabledInterrupt(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.execute(0x76, &mem)
+	mem.requestInterrupt(timerInterrupt)
+	reg.step(&mem)
+	if !reg.halted {
+		t.Errorf("CPU woke up on an interrupt that is not enabled")
+	}
+}
+
+func TestHaltBug(t *testing.T) {
+	var reg Register
+	var mem Memory
+	// HALT; INC A
+	mem.rom[0] = 0x76
+	mem.rom[1] = 0x3c
+	mem.writeByte(0xffff, 0x01)
+	mem.requestInterrupt(vblankInterrupt)
+	reg.step(&mem)
+	if reg.halted {
+		t.Errorf("CPU halted with IME reset and a pending interrupt")
+	}
+	reg.step(&mem)
+	reg.step(&mem)
+	if reg.a != 2 {
+		t.Errorf("%d in register A, expected INC A to run twice", reg.a)
+	}
+	if reg.pc != 2 {
+		t.Errorf("%d in program counter, expected 2", reg.pc)
+	}
+}
+
+func TestStop(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.execute(0x10, &mem)
+	if !reg.stopped {
+		t.Errorf("CPU not stopped")
+	}
+	if reg.pc != 2 {
+		t.Errorf("%d in program counter, expected 2", reg.pc)
+	}
+	reg.step(&mem)
+	if !reg.stopped || reg.clock != 4 {
+		t.Errorf("CPU woke up without a button press")
+	}
+	mem.requestInterrupt(joypadInterrupt)
+	reg.step(&mem)
+	if reg.stopped {
+		t.Errorf("CPU still stopped after a button press")
+	}
+}
+
+func TestStopWakeOnJoypadLine(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.execute(0x10, &mem)
+	// a button held on a selected row, without a joypad interrupt request
+	mem.joypad.pressed = 1 << buttonStart
+	reg.step(&mem)
+	if reg.stopped {
+		t.Errorf("CPU still stopped with a joypad line low")
+	}
+}
+
+func TestStopSpeedSwitch(t *testing.T) {
+	var reg Register
+	var mem Memory
+	mem.cgb = true
+	mem.writeByte(0xff4d, 0x01)
+	reg.execute(0x10, &mem)
+	if reg.stopped {
+		t.Errorf("CPU stopped during a speed switch")
+	}
+	if !mem.doubleSpeed || mem.readByte(0xff4d) != 0xfe {
+		t.Errorf("0x%02x in KEY1 register, expected double speed 0xfe", mem.readByte(0xff4d))
+	}
+}
+
+func TestStopSpeedSwitchDmg(t *testing.T) {
+	var reg Register
+	var mem Memory
+	mem.writeByte(0xff4d, 0x01)
+	reg.execute(0x10, &mem)
+	if !reg.stopped {
+		t.Errorf("CPU not stopped outside CGB mode")
+	}
+	if mem.doubleSpeed || mem.readByte(0xff4d) != 0xff {
+		t.Errorf("0x%02x in KEY1 register, expected speed unchanged outside CGB mode", mem.readByte(0xff4d))
+	}
+}
+
+// Results of the CB rotate and shift operations (opcodes 0x00-0x3f, by opcode>>3)
+// on the value 0x85 with the carry flag set
+var cbRotateResults = []struct {
+	name   string
+	result byte
+	flags  byte
+}{
+	{"RLC", 0x0b, 0x10},
+	{"RRC", 0xc2, 0x10},
+	{"RL", 0x0b, 0x10},
+	{"RR", 0xc2, 0x10},
+	{"SLA", 0x0a, 0x10},
+	{"SRA", 0xc2, 0x10},
+	{"SWAP", 0x58, 0x00},
+	{"SRL", 0x42, 0x10},
+}
+
+var cbTargets = []string{"B", "C", "D", "E", "H", "L", "HL", "A"}
+
+func TestExecuteCb(t *testing.T) {
+	for opcode := 0; opcode < 256; opcode++ {
+		var reg Register
+		var mem Memory
+		var input byte = 0x85
+		target := cbTargets[opcode&0x07]
+		reg.pc = 0xc000
+		reg.flags = 0x10
+		mem.writeByte(0xc000, 0xcb)
+		mem.writeByte(0xc001, byte(opcode))
+		if target == "HL" {
+			reg.setHLregisters(0xc100)
+			mem.writeByte(0xc100, input)
+		} else {
+			reg.writeRegister(target, input, &mem)
+		}
+
+		expected := input
+		expectedFlags := reg.flags
+		expectedClock := 8
+		pos := uint16(opcode>>3) & 0x07
+		switch {
+		case opcode < 0x40:
+			expected = cbRotateResults[opcode>>3].result
+			expectedFlags = cbRotateResults[opcode>>3].flags
+		case opcode < 0x80:
+			// BIT: zero flag is the complement of the tested bit, half carry set, carry untouched
+			expectedFlags = 0x30
+			if !hasBit(uint16(input), pos) {
+				expectedFlags |= 0x80
+			}
+		case opcode < 0xc0:
+			expected = input &^ (1 << pos)
+		default:
+			expected = input | (1 << pos)
+		}
+		if target == "HL" {
+			expectedClock = 16
+			if opcode >= 0x40 && opcode < 0x80 {
+				expectedClock = 12
+			}
+		}
+
+		reg.execute(mem.readByte(reg.pc), &mem)
+		if result := reg.readRegister(target, &mem); result != expected {
+			t.Errorf("opcode CB %02x: 0x%02x in %s, expected 0x%02x", opcode, result, target, expected)
+		}
+		if reg.flags != expectedFlags {
+			t.Errorf("opcode CB %02x: 0x%02x in flags, expected 0x%02x", opcode, reg.flags, expectedFlags)
+		}
+		if reg.clock != expectedClock {
+			t.Errorf("opcode CB %02x: %d clock cycles, expected %d", opcode, reg.clock, expectedClock)
+		}
+		if reg.pc != 0xc002 {
+			t.Errorf("opcode CB %02x: %d in program counter, expected %d", opcode, reg.pc, 0xc002)
+		}
+	}
+}
+
+func TestExecuteCbZeroFlag(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.setHLregisters(0xc100)
+	mem.writeByte(0xc100, 0x80)
+	// SLA (HL)
+	reg.executeCb(0x26, &mem)
+	if mem.readByte(0xc100) != 0 {
+		t.Errorf("%d at memory address HL, expected 0", mem.readByte(0xc100))
+	}
+	if reg.flags != 0x90 {
+		t.Errorf("0x%02x in flags, expected zero and carry flags", reg.flags)
+	}
+	// RL (HL) shifts the carry back in
+	reg.executeCb(0x16, &mem)
+	if mem.readByte(0xc100) != 1 || reg.flags != 0 {
+		t.Errorf("0x%02x at memory address HL and 0x%02x in flags, expected 1 and 0", mem.readByte(0xc100), reg.flags)
+	}
+}
+
+func TestInstructionCycles(t *testing.T) {
+	for _, test := range []struct {
+		opcode byte
+		flags  byte
+		clock  int
+	}{
+		{0x00, 0x00, 4},  // NOP
+		{0x01, 0x00, 12}, // LD BC,nn
+		{0xc3, 0x00, 16}, // JP nn
+		{0xcd, 0x00, 24}, // CALL nn
+		{0xc9, 0x00, 16}, // RET
+		{0x20, 0x80, 8},  // JR NZ,n not taken
+		{0x20, 0x00, 12}, // JR NZ,n taken
+		{0xc2, 0x80, 12}, // JP NZ,nn not taken
+		{0xc2, 0x00, 16}, // JP NZ,nn taken
+		{0xc4, 0x80, 12}, // CALL NZ,nn not taken
+		{0xc4, 0x00, 24}, // CALL NZ,nn taken
+		{0xc0, 0x80, 8},  // RET NZ not taken
+		{0xc0, 0x00, 20}, // RET NZ taken
+	} {
+		var reg Register
+		var mem Memory
+		reg.pc = 0xc000
+		reg.sp = 0xdff0
+		reg.flags = test.flags
+		reg.execute(test.opcode, &mem)
+		if reg.clock != test.clock {
+			t.Errorf("opcode %02x with flags %02x: %d clock cycles, expected %d", test.opcode, test.flags, reg.clock, test.clock)
+		}
+	}
+}
+
+func TestCallKeepsIe(t *testing.T) {
+	var reg Register
+	var mem Memory
+	// post-boot stack pointer, right below IE
+	reg.sp = 0xfffe
+	reg.pc = 0x0150
+	mem.writeByte(0xffff, 0x1f)
+	reg.callnn(0x0200, &mem)
+	if mem.readByte(0xffff) != 0x1f {
+		t.Errorf("0x%02x in IE register after CALL, expected 0x1f", mem.readByte(0xffff))
+	}
+	reg.ret(&mem)
+	if reg.pc != 0x0152 || reg.sp != 0xfffe {
+		t.Errorf("returned to 0x%04x with SP 0x%04x, expected 0x0152 and 0xfffe", reg.pc, reg.sp)
+	}
+}
+
+func TestAddHLSP(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.setHLregisters(0x0102)
+	reg.sp = 0xdff0
+	reg.execute(0x39, &mem)
+	if reg.getHLregister() != 0xe0f2 {
+		t.Errorf("0x%04x in HL, expected 0xe0f2", reg.getHLregister())
+	}
+}
+
+func TestLdACOpcode(t *testing.T) {
+	var reg Register
+	var mem Memory
+	reg.pc = 0xc000
+	reg.c = 0x0f
+	mem.requestInterrupt(timerInterrupt)
+	reg.execute(0xf2, &mem)
+	// the upper 3 bits of IF read as 1
+	if reg.a != 0xe4 {
+		t.Errorf("0x%02x in register A, expected IF 0xe4", reg.a)
+	}
+	if reg.pc != 0xc001 {
+		t.Errorf("0x%04x in program counter, expected 0xc001", reg.pc)
+	}
+}
@@ -0,0 +1,84 @@
+package gb
+
+// Interrupt sources, given as their bit position in the IE (0xFFFF) and IF (0xFF0F) registers.
+// The bit position is also the priority: lower bits are serviced first.
+// see https://gbdev.io/pandocs/Interrupts.html
+const (
+	vblankInterrupt byte = 0
+	lcdInterrupt    byte = 1
+	timerInterrupt  byte = 2
+	serialInterrupt byte = 3
+	joypadInterrupt byte = 4
+)
+
+// Handler addresses for each interrupt source, indexed by bit position
+var interruptVectors [5]uint16 = [5]uint16{0x40, 0x48, 0x50, 0x58, 0x60}
+
+// Request interrupt by setting its bit in the IF register
+func (mem *Memory) requestInterrupt(interrupt byte) {
+	mem.io[0x0F] |= 1 << interrupt
+}
+
+// Returns the interrupts that are both requested and enabled
+func (mem *Memory) pendingInterrupts() byte {
+	return mem.ie & mem.io[0x0F] & 0x1F
+}
+
+/* *************************************** */
+/* Interrupt opcodes                       */
+/* *************************************** */
+
+// Disable interrupts, cancelling a pending EI
+func (reg *Register) di() {
+	reg.ime = false
+	reg.imeDelay = 0
+}
+
+// Enable interrupts after the instruction following EI
+func (reg *Register) ei() {
+	if !reg.ime {
+		reg.imeDelay = 2
+	}
+}
+
+// Pop value from stack, jump and enable interrupts immediately
+func (reg *Register) reti(mem *Memory) {
+	reg.ret(mem)
+	reg.ime = true
+	reg.imeDelay = 0
+}
+
+// Counts down the EI delay, called at the end of every instruction
+func (reg *Register) updateIme() {
+	if reg.imeDelay > 0 {
+		reg.imeDelay--
+		if reg.imeDelay == 0 {
+			reg.ime = true
+		}
+	}
+}
+
+// Service the highest priority pending interrupt, if interrupts are enabled.
+// Dispatching pushes PC, jumps to the interrupt vector and takes 20 cycles,
+// which are added to the clock of the last instruction.
+func (reg *Register) handleInterrupts(mem *Memory) bool {
+	if !reg.ime {
+		return false
+	}
+	pending := mem.pendingInterrupts()
+	if pending == 0 {
+		return false
+	}
+	for interrupt := byte(0); interrupt < 5; interrupt++ {
+		if hasBit(uint16(pending), uint16(interrupt)) {
+			reg.ime = false
+			mem.io[0x0F] &^= 1 << interrupt
+			reg.sp -= 2
+			mem.writeWord(reg.sp, reg.pc)
+			reg.pc = interruptVectors[interrupt]
+			reg.clock += 20
+			return true
+		}
+	}
+	return false
+}
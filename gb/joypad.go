@@ -0,0 +1,61 @@
+package gb
+
+// Buttons, given as their bit position in Joypad.pressed. The lower nibble
+// holds the direction keys and the upper nibble the action buttons, in the
+// order of the P1 register lines.
+const (
+	buttonRight  byte = 0
+	buttonLeft   byte = 1
+	buttonUp     byte = 2
+	buttonDown   byte = 3
+	buttonA      byte = 4
+	buttonB      byte = 5
+	buttonSelect byte = 6
+	buttonStart  byte = 7
+)
+
+// see https://gbdev.io/pandocs/Joypad_Input.html
+type Joypad struct {
+	// one bit per button, set while the button is held
+	pressed byte
+	// bits 4 (directions) and 5 (actions) of P1, a row is selected when its bit is reset
+	selection byte
+}
+
+// Returns the P1 register: the lines of the selected rows are pulled low by held buttons
+func (joypad *Joypad) readByte() byte {
+	var lines byte = 0x0F
+	if !hasBit(uint16(joypad.selection), 4) {
+		lines &^= joypad.pressed & 0x0F
+	}
+	if !hasBit(uint16(joypad.selection), 5) {
+		lines &^= joypad.pressed >> 4
+	}
+	return 0xC0 | joypad.selection | lines
+}
+
+// Select the button rows, only bits 4 and 5 of P1 are writable
+func (joypad *Joypad) writeByte(value byte, mem *Memory) {
+	before := joypad.readByte()
+	joypad.selection = value & 0x30
+	joypad.checkInterrupt(before, mem)
+}
+
+// Press or release button
+func (joypad *Joypad) setButton(button byte, pressed bool, mem *Memory) {
+	before := joypad.readByte()
+	if pressed {
+		joypad.pressed |= 1 << button
+	} else {
+		joypad.pressed &^= 1 << button
+	}
+	joypad.checkInterrupt(before, mem)
+}
+
+// Request the joypad interrupt if any P1 line went from high to low
+func (joypad *Joypad) checkInterrupt(before byte, mem *Memory) {
+	after := joypad.readByte()
+	if before&^after&0x0F != 0 {
+		mem.requestInterrupt(joypadInterrupt)
+	}
+}
@@ -0,0 +1,42 @@
+package gb
+
+import "testing"
+
+func TestJoypadRows(t *testing.T) {
+	var mem Memory
+	mem.joypad.setButton(buttonStart, true, &mem)
+	mem.joypad.setButton(buttonLeft, true, &mem)
+	// select action buttons
+	mem.writeByte(0xff00, 0x10)
+	if mem.readByte(0xff00) != 0xd7 {
+		t.Errorf("0x%02x in P1 register, expected 0x%02x", mem.readByte(0xff00), 0xd7)
+	}
+	// select direction keys
+	mem.writeByte(0xff00, 0x20)
+	if mem.readByte(0xff00) != 0xed {
+		t.Errorf("0x%02x in P1 register, expected 0x%02x", mem.readByte(0xff00), 0xed)
+	}
+	// no row selected
+	mem.writeByte(0xff00, 0x30)
+	if mem.readByte(0xff00) != 0xff {
+		t.Errorf("0x%02x in P1 register, expected 0x%02x", mem.readByte(0xff00), 0xff)
+	}
+}
+
+func TestJoypadInterrupt(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff00, 0x20)
+	mem.joypad.setButton(buttonA, true, &mem)
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(joypadInterrupt)) {
+		t.Errorf("joypad interrupt requested for a button in an unselected row")
+	}
+	mem.joypad.setButton(buttonDown, true, &mem)
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(joypadInterrupt)) {
+		t.Errorf("joypad interrupt not requested")
+	}
+	mem.writeByte(0xff0f, 0)
+	mem.joypad.setButton(buttonDown, false, &mem)
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(joypadInterrupt)) {
+		t.Errorf("joypad interrupt requested on release")
+	}
+}
@@ -0,0 +1,99 @@
+package gb
+
+import "bytes"
+
+// see https://gbdev.io/pandocs/MBC1.html
+type Mbc1 struct {
+	rom        []byte
+	ram        []byte
+	ramEnabled bool
+	// lower 5 bits of the ROM bank number
+	romBank byte
+	// 2 bit register selecting the upper ROM bank bits or the RAM bank
+	upperBank byte
+	// banking mode: in mode 1 the upper register also applies to 0x0000-0x3FFF and RAM
+	mode byte
+	// MBC1M multicarts only wire 4 bits of the ROM bank register
+	multicart bool
+}
+
+func newMbc1(rom []byte, ramSize int) *Mbc1 {
+	return &Mbc1{
+		rom:       rom,
+		ram:       make([]byte, ramSize),
+		romBank:   1,
+		multicart: isMbc1Multicart(rom),
+	}
+}
+
+// MBC1M multicarts are 8 Mbit cartridges holding a game with its own header every 256 KiB.
+// Detect them by looking for the Nintendo logo of the game in bank 0x10.
+func isMbc1Multicart(rom []byte) bool {
+	if len(rom) != 0x100000 {
+		return false
+	}
+	logo := rom[0x104:0x134]
+	return bytes.Equal(rom[0x40104:0x40134], logo)
+}
+
+// Returns the ROM offset of bank
+func (mbc *Mbc1) romOffset(bank int, address uint16) int {
+	banks := len(mbc.rom) / 0x4000
+	return (bank%banks)*0x4000 + int(address&0x3FFF)
+}
+
+func (mbc *Mbc1) readByte(address uint16) byte {
+	shift := 5
+	romBank := int(mbc.romBank)
+	if mbc.multicart {
+		shift = 4
+		romBank &= 0x0F
+	}
+	switch {
+	case address < 0x4000:
+		var bank int
+		if mbc.mode == 1 {
+			bank = int(mbc.upperBank) << shift
+		}
+		return mbc.rom[mbc.romOffset(bank, address)]
+	case address < 0x8000:
+		bank := int(mbc.upperBank)<<shift | romBank
+		return mbc.rom[mbc.romOffset(bank, address)]
+	case address >= 0xA000 && address < 0xC000:
+		if !mbc.ramEnabled || len(mbc.ram) == 0 {
+			return 0xFF
+		}
+		return mbc.ram[mbc.ramOffset(address)]
+	}
+	return 0xFF
+}
+
+func (mbc *Mbc1) writeByte(address uint16, value byte) {
+	switch {
+	case address < 0x2000:
+		mbc.ramEnabled = value&0x0F == 0x0A
+	case address < 0x4000:
+		mbc.romBank = value & 0x1F
+		// bank 0 can not be selected in the switchable area
+		if mbc.romBank == 0 {
+			mbc.romBank = 1
+		}
+	case address < 0x6000:
+		mbc.upperBank = value & 0x03
+	case address < 0x8000:
+		mbc.mode = value & 0x01
+	case address >= 0xA000 && address < 0xC000:
+		if mbc.ramEnabled && len(mbc.ram) > 0 {
+			mbc.ram[mbc.ramOffset(address)] = value
+		}
+	}
+}
+
+// Returns the external RAM offset of address
+func (mbc *Mbc1) ramOffset(address uint16) int {
+	var bank int
+	if mbc.mode == 1 {
+		bank = int(mbc.upperBank)
+	}
+	return (bank*0x2000 + int(address-0xA000)) % len(mbc.ram)
+}
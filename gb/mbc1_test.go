@@ -0,0 +1,99 @@
+package gb
+
+import "testing"
+
+// Returns a ROM where the first byte of every bank holds the bank number
+func makeBankedRom(cartridgeType byte, romSize byte, ramSize byte) []byte {
+	rom := makeRom(cartridgeType, romSize, ramSize)
+	for bank := 1; bank < len(rom)/0x4000; bank++ {
+		rom[bank*0x4000] = byte(bank)
+	}
+	return rom
+}
+
+func TestMbc1RomBank(t *testing.T) {
+	mbc := newMbc1(makeBankedRom(0x01, 4, 0), 0)
+	if mbc.readByte(0x4000) != 1 {
+		t.Errorf("bank %d mapped at startup, expected 1", mbc.readByte(0x4000))
+	}
+	mbc.writeByte(0x2000, 0x05)
+	if mbc.readByte(0x4000) != 5 {
+		t.Errorf("bank %d mapped, expected 5", mbc.readByte(0x4000))
+	}
+	// bank 0 is remapped to bank 1
+	mbc.writeByte(0x2000, 0x00)
+	if mbc.readByte(0x4000) != 1 {
+		t.Errorf("bank %d mapped, expected 1", mbc.readByte(0x4000))
+	}
+	// only 5 bits are used, and the bank number is masked to the ROM size
+	mbc.writeByte(0x3fff, 0xe3)
+	if mbc.readByte(0x4000) != 3 {
+		t.Errorf("bank %d mapped, expected 3", mbc.readByte(0x4000))
+	}
+	// writes must not reach the ROM
+	if mbc.rom[0x3fff] != 0 {
+		t.Errorf("write to register changed ROM content")
+	}
+}
+
+func TestMbc1LargeRom(t *testing.T) {
+	// 2 MiB
+	mbc := newMbc1(makeBankedRom(0x01, 6, 0), 0)
+	mbc.writeByte(0x2000, 0x00)
+	mbc.writeByte(0x4000, 0x02)
+	if mbc.readByte(0x4000) != 0x41 {
+		t.Errorf("bank 0x%02x mapped, expected 0x41", mbc.readByte(0x4000))
+	}
+	if mbc.readByte(0x0000) != 0x00 {
+		t.Errorf("bank 0x%02x mapped at 0x0000 in mode 0, expected 0x00", mbc.readByte(0x0000))
+	}
+	mbc.writeByte(0x6000, 0x01)
+	if mbc.readByte(0x0000) != 0x40 {
+		t.Errorf("bank 0x%02x mapped at 0x0000 in mode 1, expected 0x40", mbc.readByte(0x0000))
+	}
+}
+
+func TestMbc1Ram(t *testing.T) {
+	mbc := newMbc1(makeRom(0x03, 0, 3), 0x8000)
+	mbc.writeByte(0xa000, 0x12)
+	if mbc.readByte(0xa000) != 0xff {
+		t.Errorf("0x%02x read from disabled RAM, expected 0xff", mbc.readByte(0xa000))
+	}
+	mbc.writeByte(0x0000, 0x0a)
+	mbc.writeByte(0xa000, 0x12)
+	// RAM banking only applies in mode 1
+	mbc.writeByte(0x4000, 0x02)
+	if mbc.readByte(0xa000) != 0x12 {
+		t.Errorf("0x%02x in RAM bank 0, expected 0x12", mbc.readByte(0xa000))
+	}
+	mbc.writeByte(0x6000, 0x01)
+	mbc.writeByte(0xa000, 0x34)
+	if mbc.ram[0x4000] != 0x34 {
+		t.Errorf("0x%02x in RAM bank 2, expected 0x34", mbc.ram[0x4000])
+	}
+	mbc.writeByte(0x4000, 0x00)
+	if mbc.readByte(0xa000) != 0x12 {
+		t.Errorf("0x%02x in RAM bank 0, expected 0x12", mbc.readByte(0xa000))
+	}
+}
+
+func TestMbc1Multicart(t *testing.T) {
+	rom := makeBankedRom(0x01, 5, 0)
+	for game := 0; game < 4; game++ {
+		copy(rom[game*0x40000+0x104:], "NINTENDO LOGO")
+	}
+	mbc := newMbc1(rom, 0)
+	if !mbc.multicart {
+		t.Fatalf("MBC1M multicart not detected")
+	}
+	// upper bits select the game, only 4 bits of the ROM bank are used
+	mbc.writeByte(0x4000, 0x01)
+	mbc.writeByte(0x2000, 0x12)
+	if mbc.readByte(0x4000) != 0x12 {
+		t.Errorf("bank 0x%02x mapped, expected 0x12", mbc.readByte(0x4000))
+	}
+	mbc.writeByte(0x6000, 0x01)
+	if mbc.readByte(0x0000) != 0x10 {
+		t.Errorf("bank 0x%02x mapped at 0x0000, expected 0x10", mbc.readByte(0x0000))
+	}
+}
@@ -0,0 +1,53 @@
+package gb
+
+// see https://gbdev.io/pandocs/MBC2.html
+type Mbc2 struct {
+	rom []byte
+	// built-in 512 x 4 bits RAM, only the lower nibble of each byte is used
+	ram        [0x200]byte
+	ramEnabled bool
+	romBank    byte
+}
+
+func newMbc2(rom []byte) *Mbc2 {
+	return &Mbc2{
+		rom:     rom,
+		romBank: 1,
+	}
+}
+
+func (mbc *Mbc2) readByte(address uint16) byte {
+	switch {
+	case address < 0x4000:
+		return mbc.rom[address]
+	case address < 0x8000:
+		banks := len(mbc.rom) / 0x4000
+		return mbc.rom[(int(mbc.romBank)%banks)*0x4000+int(address-0x4000)]
+	case address >= 0xA000 && address < 0xC000:
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+		// the RAM is echoed through the whole window, upper nibbles read as 1
+		return mbc.ram[address&0x1FF] | 0xF0
+	}
+	return 0xFF
+}
+
+func (mbc *Mbc2) writeByte(address uint16, value byte) {
+	switch {
+	case address < 0x4000:
+		// bit 8 of the address selects between RAM enable and ROM bank registers
+		if hasBit(address, 8) {
+			mbc.romBank = value & 0x0F
+			if mbc.romBank == 0 {
+				mbc.romBank = 1
+			}
+		} else {
+			mbc.ramEnabled = value&0x0F == 0x0A
+		}
+	case address >= 0xA000 && address < 0xC000:
+		if mbc.ramEnabled {
+			mbc.ram[address&0x1FF] = value & 0x0F
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package gb
+
+import "testing"
+
+func TestMbc2RomBank(t *testing.T) {
+	mbc := newMbc2(makeBankedRom(0x05, 3, 0))
+	// address bit 8 set selects the ROM bank register
+	mbc.writeByte(0x2100, 0x05)
+	if mbc.readByte(0x4000) != 5 {
+		t.Errorf("bank %d mapped, expected 5", mbc.readByte(0x4000))
+	}
+	mbc.writeByte(0x0100, 0x00)
+	if mbc.readByte(0x4000) != 1 {
+		t.Errorf("bank %d mapped, expected 1", mbc.readByte(0x4000))
+	}
+	// address bit 8 reset selects RAM enable, even in the 0x2000-0x3fff range
+	mbc.writeByte(0x2000, 0x0a)
+	if !mbc.ramEnabled || mbc.readByte(0x4000) != 1 {
+		t.Errorf("write with address bit 8 reset did not enable RAM")
+	}
+}
+
+func TestMbc2Ram(t *testing.T) {
+	mbc := newMbc2(makeRom(0x06, 0, 0))
+	mbc.writeByte(0x0000, 0x0a)
+	mbc.writeByte(0xa010, 0x5c)
+	if mbc.readByte(0xa010) != 0xfc {
+		t.Errorf("0x%02x in RAM, expected 0xfc", mbc.readByte(0xa010))
+	}
+	// the 512 bytes are echoed through 0xa000-0xbfff
+	if mbc.readByte(0xa210) != 0xfc || mbc.readByte(0xbe10) != 0xfc {
+		t.Errorf("RAM not echoed")
+	}
+	mbc.writeByte(0x0000, 0x00)
+	if mbc.readByte(0xa010) != 0xff {
+		t.Errorf("0x%02x read from disabled RAM, expected 0xff", mbc.readByte(0xa010))
+	}
+}
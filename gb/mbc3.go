@@ -0,0 +1,222 @@
+package gb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// see https://gbdev.io/pandocs/MBC3.html
+type Mbc3 struct {
+	rom []byte
+	ram []byte
+	// enables both RAM and RTC registers
+	ramEnabled bool
+	romBank    byte
+	// 0x00-0x03 select a RAM bank, 0x08-0x0C an RTC register
+	ramBank byte
+	// last value written to the latch register, latching happens on a 0 to 1 write sequence
+	latch  byte
+	hasRtc bool
+	rtc    Rtc
+}
+
+// Real time clock of MBC3 cartridges. Registers are indexed as selected through
+// 0x4000-0x5FFF minus 0x08: seconds, minutes, hours, day counter low and high.
+type Rtc struct {
+	registers [5]byte
+	latched   [5]byte
+	// wall time the registers were last brought up to date
+	lastUpdate time.Time
+	// clock source, can be replaced to control time
+	now func() time.Time
+}
+
+// Day counter high register bits
+const (
+	rtcDayHigh  byte = 0x01
+	rtcHalt     byte = 0x40
+	rtcDayCarry byte = 0x80
+)
+
+// Size of the RTC footer appended to the save RAM by BGB and VBA-M
+const rtcFooterSize int = 48
+
+func newMbc3(rom []byte, ramSize int, hasRtc bool) *Mbc3 {
+	mbc := &Mbc3{
+		rom:     rom,
+		ram:     make([]byte, ramSize),
+		romBank: 1,
+		hasRtc:  hasRtc,
+	}
+	mbc.rtc.now = time.Now
+	mbc.rtc.lastUpdate = mbc.rtc.now()
+	return mbc
+}
+
+func (mbc *Mbc3) readByte(address uint16) byte {
+	switch {
+	case address < 0x4000:
+		return mbc.rom[address]
+	case address < 0x8000:
+		banks := len(mbc.rom) / 0x4000
+		return mbc.rom[(int(mbc.romBank)%banks)*0x4000+int(address-0x4000)]
+	case address >= 0xA000 && address < 0xC000:
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+		if mbc.ramBank >= 0x08 && mbc.ramBank <= 0x0C {
+			if !mbc.hasRtc {
+				return 0xFF
+			}
+			return mbc.rtc.latched[mbc.ramBank-0x08]
+		}
+		if len(mbc.ram) == 0 {
+			return 0xFF
+		}
+		return mbc.ram[mbc.ramOffset(address)]
+	}
+	return 0xFF
+}
+
+func (mbc *Mbc3) writeByte(address uint16, value byte) {
+	switch {
+	case address < 0x2000:
+		mbc.ramEnabled = value&0x0F == 0x0A
+	case address < 0x4000:
+		mbc.romBank = value & 0x7F
+		if mbc.romBank == 0 {
+			mbc.romBank = 1
+		}
+	case address < 0x6000:
+		mbc.ramBank = value
+	case address < 0x8000:
+		if mbc.latch == 0x00 && value == 0x01 && mbc.hasRtc {
+			mbc.rtc.latch()
+		}
+		mbc.latch = value
+	case address >= 0xA000 && address < 0xC000:
+		if !mbc.ramEnabled {
+			return
+		}
+		if mbc.ramBank >= 0x08 && mbc.ramBank <= 0x0C {
+			if mbc.hasRtc {
+				mbc.rtc.writeRegister(mbc.ramBank-0x08, value)
+			}
+		} else if len(mbc.ram) > 0 {
+			mbc.ram[mbc.ramOffset(address)] = value
+		}
+	}
+}
+
+// Returns the external RAM offset of address
+func (mbc *Mbc3) ramOffset(address uint16) int {
+	return (int(mbc.ramBank&0x03)*0x2000 + int(address-0xA000)) % len(mbc.ram)
+}
+
+// Returns the content of the battery backed RAM, followed by the RTC footer for
+// cartridges with a timer
+func (mbc *Mbc3) saveData() []byte {
+	data := append([]byte{}, mbc.ram...)
+	if mbc.hasRtc {
+		data = append(data, mbc.rtc.footer()...)
+	}
+	return data
+}
+
+// Restore the battery backed RAM and the RTC state saved by saveData
+func (mbc *Mbc3) loadData(data []byte) error {
+	if err := loadRam(mbc.ram, data); err != nil {
+		return err
+	}
+	footer := data[len(mbc.ram):]
+	if mbc.hasRtc && len(footer) > 0 {
+		return mbc.rtc.loadFooter(footer)
+	}
+	return nil
+}
+
+/* *************************************** */
+/* Real time clock                         */
+/* *************************************** */
+
+// Advance the registers by the wall time elapsed since the last update
+func (rtc *Rtc) update() {
+	now := rtc.now()
+	if rtc.registers[4]&rtcHalt != 0 {
+		rtc.lastUpdate = now
+		return
+	}
+	elapsed := int64(now.Sub(rtc.lastUpdate) / time.Second)
+	if elapsed <= 0 {
+		return
+	}
+	rtc.lastUpdate = rtc.lastUpdate.Add(time.Duration(elapsed) * time.Second)
+
+	seconds := int64(rtc.registers[0]) + elapsed
+	minutes := int64(rtc.registers[1]) + seconds/60
+	hours := int64(rtc.registers[2]) + minutes/60
+	days := int64(rtc.registers[3]) | int64(rtc.registers[4]&rtcDayHigh)<<8
+	days += hours / 24
+	rtc.registers[0] = byte(seconds % 60)
+	rtc.registers[1] = byte(minutes % 60)
+	rtc.registers[2] = byte(hours % 24)
+	rtc.registers[3] = byte(days)
+	rtc.registers[4] = rtc.registers[4]&^rtcDayHigh | byte(days>>8)&rtcDayHigh
+	// the carry bit stays set until cleared by the game
+	if days > 0x1FF {
+		rtc.registers[4] |= rtcDayCarry
+	}
+}
+
+// Copy the current time to the latched registers the game reads
+func (rtc *Rtc) latch() {
+	rtc.update()
+	rtc.latched = rtc.registers
+}
+
+// Set register index, counting restarts from the written value
+func (rtc *Rtc) writeRegister(index byte, value byte) {
+	rtc.update()
+	var masks [5]byte = [5]byte{0x3F, 0x3F, 0x1F, 0xFF, 0xC1}
+	rtc.registers[index] = value & masks[index]
+	rtc.latched[index] = rtc.registers[index]
+	if index == 0 {
+		// writing seconds resets the sub-second counter
+		rtc.lastUpdate = rtc.now()
+	}
+}
+
+// Returns the RTC state in the BGB/VBA-M footer format: current then latched
+// registers as 32 bit little endian values, followed by a 64 bit UNIX timestamp
+func (rtc *Rtc) footer() []byte {
+	rtc.update()
+	footer := make([]byte, rtcFooterSize)
+	for i := 0; i < 5; i++ {
+		binary.LittleEndian.PutUint32(footer[i*4:], uint32(rtc.registers[i]))
+		binary.LittleEndian.PutUint32(footer[20+i*4:], uint32(rtc.latched[i]))
+	}
+	binary.LittleEndian.PutUint64(footer[40:], uint64(rtc.lastUpdate.Unix()))
+	return footer
+}
+
+// Restore the RTC state from a footer, advancing it by the time elapsed since it was saved.
+// Footers with a 32 bit timestamp are also accepted.
+func (rtc *Rtc) loadFooter(footer []byte) error {
+	var timestamp int64
+	switch len(footer) {
+	case rtcFooterSize:
+		timestamp = int64(binary.LittleEndian.Uint64(footer[40:]))
+	case rtcFooterSize - 4:
+		timestamp = int64(binary.LittleEndian.Uint32(footer[40:]))
+	default:
+		return fmt.Errorf("RTC footer is %d bytes long, expected %d or %d", len(footer), rtcFooterSize, rtcFooterSize-4)
+	}
+	for i := 0; i < 5; i++ {
+		rtc.registers[i] = byte(binary.LittleEndian.Uint32(footer[i*4:]))
+		rtc.latched[i] = byte(binary.LittleEndian.Uint32(footer[20+i*4:]))
+	}
+	rtc.lastUpdate = time.Unix(timestamp, 0)
+	rtc.update()
+	return nil
+}
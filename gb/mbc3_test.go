@@ -0,0 +1,115 @@
+package gb
+
+import (
+	"testing"
+	"time"
+)
+
+// Clock source advanced manually by tests
+type fakeClock struct {
+	current time.Time
+}
+
+func (clock *fakeClock) now() time.Time {
+	return clock.current
+}
+
+func newTestMbc3(clock *fakeClock) *Mbc3 {
+	mbc := newMbc3(makeBankedRom(0x10, 6, 3), 0x8000, true)
+	mbc.rtc.now = clock.now
+	mbc.rtc.lastUpdate = clock.now()
+	mbc.writeByte(0x0000, 0x0a)
+	return mbc
+}
+
+// Latch the clock and return the RTC registers
+func readRtc(mbc *Mbc3) [5]byte {
+	var registers [5]byte
+	mbc.writeByte(0x6000, 0x00)
+	mbc.writeByte(0x6000, 0x01)
+	for i := byte(0); i < 5; i++ {
+		mbc.writeByte(0x4000, 0x08+i)
+		registers[i] = mbc.readByte(0xa000)
+	}
+	return registers
+}
+
+func TestMbc3Banks(t *testing.T) {
+	var clock fakeClock
+	mbc := newTestMbc3(&clock)
+	mbc.writeByte(0x2000, 0x45)
+	if mbc.readByte(0x4000) != 0x45 {
+		t.Errorf("bank 0x%02x mapped, expected 0x45", mbc.readByte(0x4000))
+	}
+	mbc.writeByte(0x2000, 0x00)
+	if mbc.readByte(0x4000) != 0x01 {
+		t.Errorf("bank 0x%02x mapped, expected 0x01", mbc.readByte(0x4000))
+	}
+	mbc.writeByte(0x4000, 0x03)
+	mbc.writeByte(0xa000, 0x12)
+	if mbc.ram[0x6000] != 0x12 {
+		t.Errorf("0x%02x in RAM bank 3, expected 0x12", mbc.ram[0x6000])
+	}
+}
+
+func TestMbc3Rtc(t *testing.T) {
+	clock := fakeClock{time.Unix(1000000, 0)}
+	mbc := newTestMbc3(&clock)
+	clock.current = clock.current.Add(25*time.Hour + 2*time.Minute + 3*time.Second)
+	registers := readRtc(mbc)
+	if registers != [5]byte{3, 2, 1, 1, 0} {
+		t.Errorf("%v in RTC registers, expected [3 2 1 1 0]", registers)
+	}
+	// latched registers do not change until the next latch
+	clock.current = clock.current.Add(time.Second)
+	mbc.writeByte(0x4000, 0x08)
+	if mbc.readByte(0xa000) != 3 {
+		t.Errorf("%d in latched seconds, expected 3", mbc.readByte(0xa000))
+	}
+}
+
+func TestMbc3RtcHaltAndCarry(t *testing.T) {
+	clock := fakeClock{time.Unix(1000000, 0)}
+	mbc := newTestMbc3(&clock)
+	mbc.writeByte(0x4000, 0x0c)
+	mbc.writeByte(0xa000, rtcHalt)
+	clock.current = clock.current.Add(time.Hour)
+	if registers := readRtc(mbc); registers != [5]byte{0, 0, 0, 0, rtcHalt} {
+		t.Errorf("%v in RTC registers, expected halted clock", registers)
+	}
+	// day 511, 23:59:59
+	for i, value := range []byte{59, 59, 23, 0xff, rtcDayHigh} {
+		mbc.writeByte(0x4000, 0x08+byte(i))
+		mbc.writeByte(0xa000, value)
+	}
+	clock.current = clock.current.Add(time.Second)
+	if registers := readRtc(mbc); registers != [5]byte{0, 0, 0, 0, rtcDayCarry} {
+		t.Errorf("%v in RTC registers, expected day counter overflow", registers)
+	}
+}
+
+func TestMbc3SaveData(t *testing.T) {
+	clock := fakeClock{time.Unix(1000000, 0)}
+	mbc := newTestMbc3(&clock)
+	mbc.writeByte(0x4000, 0x00)
+	mbc.writeByte(0xa000, 0x42)
+	clock.current = clock.current.Add(10 * time.Second)
+	data := mbc.saveData()
+	if len(data) != 0x8000+rtcFooterSize {
+		t.Fatalf("%d bytes of save data, expected %d", len(data), 0x8000+rtcFooterSize)
+	}
+
+	// the emulator is closed for one minute
+	clock.current = clock.current.Add(time.Minute)
+	restored := newTestMbc3(&clock)
+	if err := restored.loadData(data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	restored.writeByte(0x4000, 0x00)
+	if restored.readByte(0xa000) != 0x42 {
+		t.Errorf("0x%02x in RAM, expected 0x42", restored.readByte(0xa000))
+	}
+	if registers := readRtc(restored); registers != [5]byte{10, 1, 0, 0, 0} {
+		t.Errorf("%v in RTC registers, expected [10 1 0 0 0]", registers)
+	}
+}
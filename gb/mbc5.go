@@ -0,0 +1,66 @@
+package gb
+
+// see https://gbdev.io/pandocs/MBC5.html
+type Mbc5 struct {
+	rom        []byte
+	ram        []byte
+	ramEnabled bool
+	// 9 bit ROM bank number, bank 0 can be mapped at 0x4000-0x7FFF
+	romBank uint16
+	ramBank byte
+	// on rumble cartridges bit 3 of the RAM bank register drives the motor
+	rumble   bool
+	rumbling bool
+}
+
+func newMbc5(rom []byte, ramSize int, rumble bool) *Mbc5 {
+	return &Mbc5{
+		rom:     rom,
+		ram:     make([]byte, ramSize),
+		romBank: 1,
+		rumble:  rumble,
+	}
+}
+
+func (mbc *Mbc5) readByte(address uint16) byte {
+	switch {
+	case address < 0x4000:
+		return mbc.rom[address]
+	case address < 0x8000:
+		banks := len(mbc.rom) / 0x4000
+		return mbc.rom[(int(mbc.romBank)%banks)*0x4000+int(address-0x4000)]
+	case address >= 0xA000 && address < 0xC000:
+		if !mbc.ramEnabled || len(mbc.ram) == 0 {
+			return 0xFF
+		}
+		return mbc.ram[mbc.ramOffset(address)]
+	}
+	return 0xFF
+}
+
+func (mbc *Mbc5) writeByte(address uint16, value byte) {
+	switch {
+	case address < 0x2000:
+		mbc.ramEnabled = value&0x0F == 0x0A
+	case address < 0x3000:
+		mbc.romBank = mbc.romBank&0x100 | uint16(value)
+	case address < 0x4000:
+		mbc.romBank = mbc.romBank&0xFF | uint16(value&0x01)<<8
+	case address < 0x6000:
+		if mbc.rumble {
+			mbc.rumbling = hasBit(uint16(value), 3)
+			mbc.ramBank = value & 0x07
+		} else {
+			mbc.ramBank = value & 0x0F
+		}
+	case address >= 0xA000 && address < 0xC000:
+		if mbc.ramEnabled && len(mbc.ram) > 0 {
+			mbc.ram[mbc.ramOffset(address)] = value
+		}
+	}
+}
+
+// Returns the external RAM offset of address
+func (mbc *Mbc5) ramOffset(address uint16) int {
+	return (int(mbc.ramBank)*0x2000 + int(address-0xA000)) % len(mbc.ram)
+}
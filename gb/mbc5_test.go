@@ -0,0 +1,64 @@
+package gb
+
+import "testing"
+
+func TestMbc5RomBank(t *testing.T) {
+	// 8 MiB, 512 banks
+	rom := makeBankedRom(0x19, 8, 0)
+	rom[0x1ff*0x4000+1] = 0x01
+	mbc := newMbc5(rom, 0, false)
+	if mbc.readByte(0x4000) != 1 {
+		t.Errorf("bank %d mapped at startup, expected 1", mbc.readByte(0x4000))
+	}
+	// unlike MBC1, bank 0 can be mapped in the switchable area
+	mbc.writeByte(0x2000, 0x00)
+	if mbc.readByte(0x4000) != 0 {
+		t.Errorf("bank %d mapped, expected 0", mbc.readByte(0x4000))
+	}
+	// 9th bit of the bank number
+	mbc.writeByte(0x2000, 0xff)
+	mbc.writeByte(0x3000, 0x01)
+	if mbc.readByte(0x4000) != 0xff || mbc.readByte(0x4001) != 0x01 {
+		t.Errorf("bank 0x%x mapped, expected 0x1ff", mbc.romBank)
+	}
+	mbc.writeByte(0x3000, 0x00)
+	if mbc.romBank != 0xff {
+		t.Errorf("bank 0x%x mapped, expected 0xff", mbc.romBank)
+	}
+	// 0x2000-0x2fff only holds the lower 8 bits
+	mbc.writeByte(0x3000, 0x01)
+	mbc.writeByte(0x2fff, 0x00)
+	if mbc.romBank != 0x100 {
+		t.Errorf("bank 0x%x mapped, expected 0x100", mbc.romBank)
+	}
+}
+
+func TestMbc5Ram(t *testing.T) {
+	mbc := newMbc5(makeRom(0x1b, 0, 4), 0x20000, false)
+	mbc.writeByte(0x0000, 0x0a)
+	mbc.writeByte(0x4000, 0x0f)
+	mbc.writeByte(0xbfff, 0x12)
+	if mbc.ram[0x1ffff] != 0x12 {
+		t.Errorf("0x%02x at the end of RAM bank 15, expected 0x12", mbc.ram[0x1ffff])
+	}
+	mbc.writeByte(0x0000, 0x00)
+	if mbc.readByte(0xbfff) != 0xff {
+		t.Errorf("0x%02x read from disabled RAM, expected 0xff", mbc.readByte(0xbfff))
+	}
+}
+
+func TestMbc5Rumble(t *testing.T) {
+	mbc := newMbc5(makeRom(0x1e, 0, 3), 0x8000, true)
+	mbc.writeByte(0x0000, 0x0a)
+	mbc.writeByte(0x4000, 0x09)
+	if !mbc.rumbling {
+		t.Errorf("rumble motor not started")
+	}
+	if mbc.ramBank != 1 {
+		t.Errorf("RAM bank %d selected, expected 1", mbc.ramBank)
+	}
+	mbc.writeByte(0x4000, 0x01)
+	if mbc.rumbling {
+		t.Errorf("rumble motor not stopped")
+	}
+}
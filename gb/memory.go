@@ -0,0 +1,179 @@
+package gb
+
+// see https://gbdev.gg8.se/wiki/articles/Memory_Map
+type Memory struct {
+	rom  [0x8000]byte
+	vram [0x2000]byte
+	eram [0x2000]byte
+	wram [0x2000]byte
+	oam  [0x100]byte
+	io   [0x100]byte
+	hram [0x80]byte
+	ie   byte
+	// running in CGB mode: CGB hardware and a cartridge supporting it
+	cgb bool
+	// CGB double speed mode, switched by STOP when prepared in KEY1
+	doubleSpeed bool
+	// memory mapped peripherals
+	timer  Timer
+	joypad Joypad
+	dma    Dma
+	apu    Apu
+	serial Serial
+	// cartridge memory bank controller, the flat rom and eram arrays are used instead
+	// when no cartridge is loaded
+	cartridge *Cartridge
+	mbc       Mbc
+	// set on writes to external RAM, to know when battery backed RAM must be saved
+	ramWritten bool
+	// boot ROM mapped over the cartridge ROM until 0xFF50 is written, nil when
+	// not used or once disabled
+	bootRom []byte
+}
+
+// Read byte at address from the CPU. During OAM DMA, the CPU can only access
+// the 0xFF00-0xFFFF area (IO registers and HRAM) and other reads return 0xFF.
+func (mem *Memory) readByte(address uint16) byte {
+	if mem.dma.active && address < 0xFF00 {
+		return 0xFF
+	}
+	return mem.peekByte(address)
+}
+
+// Read byte at address, ignoring the restrictions on CPU accesses
+func (mem *Memory) peekByte(address uint16) byte {
+	if mem.bootRomMapped(address) {
+		return mem.bootRom[address]
+	} else if address < 0x8000 {
+		if mem.mbc != nil {
+			return mem.mbc.readByte(address)
+		}
+		return mem.rom[address]
+	} else if address >= 0x8000 && address < 0xA000 {
+		return mem.vram[address-0x8000]
+	} else if address >= 0xA000 && address < 0xC000 {
+		if mem.mbc != nil {
+			return mem.mbc.readByte(address)
+		}
+		return mem.eram[address-0xA000]
+	} else if address >= 0xC000 && address < 0xE000 {
+		return mem.wram[address-0xC000]
+	} else if address >= 0xFE00 && address < 0xFF00 {
+		return mem.oam[address-0xFE00]
+	} else if address == 0xFF00 {
+		return mem.joypad.readByte()
+	} else if address == 0xFF01 || address == 0xFF02 {
+		return mem.serial.readByte(address)
+	} else if address >= 0xFF04 && address <= 0xFF07 {
+		return mem.timer.readByte(address)
+	} else if address == 0xFF0F {
+		// the upper 3 bits of IF are unused and always read as 1
+		return mem.io[address-0xFF00] | 0xE0
+	} else if address == 0xFF41 {
+		// bit 7 of STAT is unused and always reads as 1
+		return mem.io[address-0xFF00] | 0x80
+	} else if address == 0xFF4D {
+		// KEY1: current speed in bit 7 and prepared switch in bit 0, CGB mode only
+		if !mem.cgb {
+			return 0xFF
+		}
+		key1 := 0x7E | mem.io[address-0xFF00]&0x01
+		if mem.doubleSpeed {
+			key1 |= 0x80
+		}
+		return key1
+	} else if address >= 0xFF10 && address < 0xFF40 {
+		return mem.apu.readByte(address)
+	} else if address >= 0xFF00 && address < 0xFF80 {
+		return mem.io[address-0xFF00]
+	} else if address >= 0xFF80 && address < 0xFFFF {
+		return mem.hram[address-0xFF80]
+	} else if address == 0xFFFF {
+		return mem.ie
+	} else {
+		return 0
+	}
+}
+
+func (mem *Memory) readWord(address uint16) uint16 {
+	data := concatenateBytes(mem.readByte(address), mem.readByte(address+1))
+	return data
+}
+
+// Write byte at address from the CPU, writes outside 0xFF00-0xFFFF are ignored during OAM DMA
+func (mem *Memory) writeByte(address uint16, value byte) {
+	if mem.dma.active && address < 0xFF00 {
+		return
+	}
+	if address < 0x8000 {
+		if mem.mbc != nil {
+			mem.mbc.writeByte(address, value)
+		} else {
+			mem.rom[address] = value
+		}
+	} else if address >= 0x8000 && address < 0xA000 {
+		mem.vram[address-0x8000] = value
+	} else if address >= 0xA000 && address < 0xC000 {
+		mem.ramWritten = true
+		if mem.mbc != nil {
+			mem.mbc.writeByte(address, value)
+		} else {
+			mem.eram[address-0xA000] = value
+		}
+	} else if address >= 0xC000 && address < 0xE000 {
+		mem.wram[address-0xC000] = value
+	} else if address >= 0xFE00 && address < 0xFF00 {
+		mem.oam[address-0xFE00] = value
+	} else if address == 0xFF00 {
+		mem.joypad.writeByte(value, mem)
+	} else if address == 0xFF01 || address == 0xFF02 {
+		mem.serial.writeByte(address, value)
+	} else if address >= 0xFF04 && address <= 0xFF07 {
+		mem.timer.writeByte(address, value)
+	} else if address == 0xFF41 {
+		// the mode and coincidence bits of STAT are read-only
+		mem.io[address-0xFF00] = value&0x78 | mem.io[address-0xFF00]&0x07
+	} else if address == 0xFF46 {
+		mem.io[address-0xFF00] = value
+		mem.dma.start(value)
+	} else if address == 0xFF50 {
+		// writing a non-zero value unmaps the boot ROM until the next reset
+		if value != 0 {
+			mem.bootRom = nil
+		}
+		mem.io[address-0xFF00] = value
+	} else if address == 0xFF4D {
+		// only the speed switch preparation bit of KEY1 is writable
+		mem.io[address-0xFF00] = value & 0x01
+	} else if address >= 0xFF10 && address < 0xFF40 {
+		mem.apu.writeByte(address, value)
+	} else if address >= 0xFF00 && address < 0xFF80 {
+		mem.io[address-0xFF00] = value
+	} else if address >= 0xFF80 && address < 0xFFFF {
+		mem.hram[address-0xFF80] = value
+	} else if address == 0xFFFF {
+		mem.ie = value
+	}
+}
+
+func (mem *Memory) writeWord(address uint16, value uint16) {
+	r1, r2 := separateWord(value)
+	mem.writeByte(address, r1)
+	mem.writeByte(address+1, r2)
+}
+
+// Load the cartridge ROM rom and plug its memory bank controller
+func (mem *Memory) loadRom(rom []byte) error {
+	cart, err := newCartridge(rom)
+	if err != nil {
+		return err
+	}
+	mem.loadCartridge(cart)
+	return nil
+}
+
+// Plug cartridge cart in the memory bus
+func (mem *Memory) loadCartridge(cart *Cartridge) {
+	mem.cartridge = cart
+	mem.mbc = cart.mbc
+}
@@ -0,0 +1,74 @@
+package gb
+
+// Noise channel 4, a 15 or 7 bit linear feedback shift register
+// see https://gbdev.io/pandocs/Audio_Registers.html
+type Noise struct {
+	enabled bool
+	// the DAC is on when the upper 5 bits of NR42 are not all 0
+	dac     bool
+	lfsr    uint16
+	shift   byte
+	short   bool
+	divisor byte
+	// cycles left before the next LFSR clock
+	timer    int
+	length   Length
+	envelope Envelope
+}
+
+// Cycles between LFSR clocks for each divisor code, before the NR43 shift
+var noiseDivisors [8]int = [8]int{8, 16, 32, 48, 64, 80, 96, 112}
+
+// Write register NR40-NR44, NR40 does not exist
+func (noise *Noise) writeRegister(register int, value byte) {
+	switch register {
+	case 1:
+		noise.length.counter = 64 - int(value&0x3F)
+	case 2:
+		noise.envelope.write(value)
+		noise.dac = value&0xF8 != 0
+		if !noise.dac {
+			noise.enabled = false
+		}
+	case 3:
+		noise.shift = value >> 4
+		noise.short = hasBit(uint16(value), 3)
+		noise.divisor = value & 0x07
+	case 4:
+		noise.length.enabled = hasBit(uint16(value), 6)
+		if hasBit(uint16(value), 7) {
+			noise.trigger()
+		}
+	}
+}
+
+func (noise *Noise) trigger() {
+	noise.enabled = noise.dac
+	noise.length.trigger(64)
+	noise.timer = noiseDivisors[noise.divisor] << noise.shift
+	noise.envelope.trigger()
+	noise.lfsr = 0x7FFF
+}
+
+// Advance the channel by one cycle
+func (noise *Noise) step() {
+	noise.timer--
+	if noise.timer > 0 {
+		return
+	}
+	noise.timer = noiseDivisors[noise.divisor] << noise.shift
+	feedback := (noise.lfsr ^ noise.lfsr>>1) & 1
+	noise.lfsr = noise.lfsr>>1 | feedback<<14
+	// in 7 bit mode the feedback is also written to bit 6
+	if noise.short {
+		noise.lfsr = noise.lfsr&^(1<<6) | feedback<<6
+	}
+}
+
+// Returns the channel volume, between 0 and 15
+func (noise *Noise) output() byte {
+	if !noise.enabled || noise.lfsr&1 != 0 {
+		return 0
+	}
+	return noise.envelope.volume
+}
@@ -0,0 +1,141 @@
+package gb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Memory bank controllers with battery backed RAM. The save data uses the raw
+// RAM layout of other emulators, followed by the RTC footer on MBC3.
+type BatteryBacked interface {
+	saveData() []byte
+	loadData(data []byte) error
+}
+
+// Delay after the last write to external RAM before the save file is written
+const saveDelay time.Duration = 3 * time.Second
+
+// Save file of a battery backed cartridge
+type Battery struct {
+	path string
+	mbc  BatteryBacked
+	// external RAM was written since the save file was last written
+	pending   bool
+	lastWrite time.Time
+}
+
+// Returns the save file path for the rom at romPath: the ROM extension is replaced by .sav,
+// and the file is in saveDir when not empty
+func SavePath(romPath string, saveDir string) string {
+	path := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+	if saveDir != "" {
+		return filepath.Join(saveDir, filepath.Base(path))
+	}
+	return path
+}
+
+// Returns the battery of the cartridge loaded in mem, or nil if it has none
+func newBattery(path string, mem *Memory) *Battery {
+	if mem.cartridge == nil || !mem.cartridge.hasBattery() {
+		return nil
+	}
+	mbc, ok := mem.mbc.(BatteryBacked)
+	if !ok {
+		return nil
+	}
+	return &Battery{path: path, mbc: mbc}
+}
+
+// Restore external RAM from the save file, a missing save file is not an error
+func (battery *Battery) load() error {
+	data, err := os.ReadFile(battery.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = battery.mbc.loadData(data); err != nil {
+		return fmt.Errorf("%s: %w", battery.path, err)
+	}
+	return nil
+}
+
+// Write the save file once no write to external RAM happened for saveDelay
+func (battery *Battery) update(mem *Memory, now time.Time) error {
+	if mem.ramWritten {
+		mem.ramWritten = false
+		battery.pending = true
+		battery.lastWrite = now
+	}
+	if battery.pending && now.Sub(battery.lastWrite) >= saveDelay {
+		return battery.flush()
+	}
+	return nil
+}
+
+// Write the save file. The file is replaced atomically so that a crash never leaves a truncated save.
+func (battery *Battery) flush() error {
+	tmp := battery.path + ".tmp"
+	if err := os.WriteFile(tmp, battery.mbc.saveData(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, battery.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	battery.pending = false
+	return nil
+}
+
+/* *************************************** */
+/* Save data of each controller            */
+/* *************************************** */
+
+// Copy save data in ram, which must not be larger than the save data
+func loadRam(ram []byte, data []byte) error {
+	if len(data) < len(ram) {
+		return fmt.Errorf("save is %d bytes long, expected %d bytes of RAM", len(data), len(ram))
+	}
+	copy(ram, data)
+	return nil
+}
+
+func (mbc *RomOnly) saveData() []byte {
+	return append([]byte{}, mbc.ram[:]...)
+}
+
+func (mbc *RomOnly) loadData(data []byte) error {
+	return loadRam(mbc.ram[:], data)
+}
+
+func (mbc *Mbc1) saveData() []byte {
+	return append([]byte{}, mbc.ram...)
+}
+
+func (mbc *Mbc1) loadData(data []byte) error {
+	return loadRam(mbc.ram, data)
+}
+
+func (mbc *Mbc2) saveData() []byte {
+	return append([]byte{}, mbc.ram[:]...)
+}
+
+func (mbc *Mbc2) loadData(data []byte) error {
+	err := loadRam(mbc.ram[:], data)
+	for i := range mbc.ram {
+		mbc.ram[i] &= 0x0F
+	}
+	return err
+}
+
+func (mbc *Mbc5) saveData() []byte {
+	return append([]byte{}, mbc.ram...)
+}
+
+func (mbc *Mbc5) loadData(data []byte) error {
+	return loadRam(mbc.ram, data)
+}
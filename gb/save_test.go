@@ -0,0 +1,87 @@
+package gb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavePath(t *testing.T) {
+	if path := SavePath("roms/zelda.gb", ""); path != "roms/zelda.sav" {
+		t.Errorf("%q save path, expected %q", path, "roms/zelda.sav")
+	}
+	if path := SavePath("roms/tetris", ""); path != "roms/tetris.sav" {
+		t.Errorf("%q save path, expected %q", path, "roms/tetris.sav")
+	}
+	if path := SavePath("roms/zelda.gb", "saves"); path != filepath.Join("saves", "zelda.sav") {
+		t.Errorf("%q save path, expected %q", path, filepath.Join("saves", "zelda.sav"))
+	}
+}
+
+func newTestBattery(t *testing.T, cartridgeType byte) (*Battery, *Memory) {
+	var mem Memory
+	cart, err := newCartridge(makeRom(cartridgeType, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mem.loadCartridge(cart)
+	return newBattery(filepath.Join(t.TempDir(), "game.sav"), &mem), &mem
+}
+
+func TestBatteryDetection(t *testing.T) {
+	if battery, _ := newTestBattery(t, 0x02); battery != nil {
+		t.Errorf("battery found on a MBC1+RAM cartridge")
+	}
+	if battery, _ := newTestBattery(t, 0x03); battery == nil {
+		t.Errorf("no battery found on a MBC1+RAM+BATTERY cartridge")
+	}
+}
+
+func TestBatteryDelayedSave(t *testing.T) {
+	battery, mem := newTestBattery(t, 0x03)
+	if err := battery.load(); err != nil {
+		t.Fatalf("unexpected error loading a missing save: %s", err)
+	}
+	start := time.Unix(1000000, 0)
+	mem.writeByte(0x0000, 0x0a)
+	mem.writeByte(0xa123, 0x45)
+	battery.update(mem, start)
+	battery.update(mem, start.Add(time.Second))
+	if _, err := os.Stat(battery.path); err == nil {
+		t.Errorf("save file written right after a RAM write")
+	}
+	battery.update(mem, start.Add(saveDelay))
+	data, err := os.ReadFile(battery.path)
+	if err != nil {
+		t.Fatalf("save file not written: %s", err)
+	}
+	if len(data) != 0x2000 || data[0x123] != 0x45 {
+		t.Errorf("save file does not hold the raw RAM content")
+	}
+	if _, err := os.Stat(battery.path + ".tmp"); err == nil {
+		t.Errorf("temporary save file left behind")
+	}
+}
+
+func TestBatteryLoad(t *testing.T) {
+	battery, mem := newTestBattery(t, 0x1b)
+	data := make([]byte, 0x2000)
+	data[0x10] = 0x99
+	if err := os.WriteFile(battery.path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := battery.load(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mem.writeByte(0x0000, 0x0a)
+	if mem.readByte(0xa010) != 0x99 {
+		t.Errorf("0x%02x in RAM, expected 0x99", mem.readByte(0xa010))
+	}
+	if err := os.WriteFile(battery.path, data[:0x100], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := battery.load(); err == nil {
+		t.Errorf("no error for a truncated save file")
+	}
+}
@@ -0,0 +1,81 @@
+package gb
+
+// Serial port, without a link cable: the bits received are all 1.
+// see https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
+type Serial struct {
+	sb byte
+	sc byte
+	// bits left to shift in the current transfer
+	bits int
+	// cycles left before the next bit is shifted
+	clock int
+	// bytes sent, test ROMs print their results to the serial port
+	output []byte
+}
+
+// Cycles per bit with the 8192 Hz internal clock
+const serialBitCycles int = 512
+
+// Bytes kept in the output until they are read
+const serialOutputSize int = 4096
+
+// Start a transfer when SC requests it with the internal clock. Transfers
+// with the external clock never complete, no other Game Boy is connected.
+func (serial *Serial) start() {
+	if !hasBit(uint16(serial.sc), 7) || !hasBit(uint16(serial.sc), 0) {
+		serial.bits = 0
+		return
+	}
+	// drop the bytes nobody reads, games use the port for more than text
+	if len(serial.output) >= serialOutputSize {
+		serial.output = serial.output[:0]
+	}
+	serial.output = append(serial.output, serial.sb)
+	serial.bits = 8
+	serial.clock = serialBitCycles
+}
+
+// Advance the serial port by the given number of cycles
+func (serial *Serial) step(cycles int, mem *Memory) {
+	if serial.bits == 0 {
+		return
+	}
+	serial.clock -= cycles
+	for serial.clock <= 0 && serial.bits > 0 {
+		serial.sb = serial.sb<<1 | 1
+		serial.bits--
+		serial.clock += serialBitCycles
+		if serial.bits == 0 {
+			serial.sc &^= 0x80
+			mem.requestInterrupt(serialInterrupt)
+		}
+	}
+}
+
+// Returns the bytes sent since the last call
+func (serial *Serial) readOutput() []byte {
+	output := serial.output
+	serial.output = nil
+	return output
+}
+
+func (serial *Serial) readByte(address uint16) byte {
+	switch address {
+	case 0xFF01:
+		return serial.sb
+	case 0xFF02:
+		// unused bits of SC read as 1
+		return serial.sc | 0x7E
+	}
+	return 0xFF
+}
+
+func (serial *Serial) writeByte(address uint16, value byte) {
+	switch address {
+	case 0xFF01:
+		serial.sb = value
+	case 0xFF02:
+		serial.sc = value
+		serial.start()
+	}
+}
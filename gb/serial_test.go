@@ -0,0 +1,59 @@
+package gb
+
+import "testing"
+
+func TestSerialTransfer(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff01, 'P')
+	// start a transfer with the internal clock
+	mem.writeByte(0xff02, 0x81)
+	if mem.readByte(0xff02) != 0xff {
+		t.Errorf("0x%02X in SC register, expected 0xFF during the transfer", mem.readByte(0xff02))
+	}
+	mem.serial.step(serialBitCycles*8-4, &mem)
+	if !hasBit(uint16(mem.readByte(0xff02)), 7) || hasBit(uint16(mem.io[0x0f]), uint16(serialInterrupt)) {
+		t.Errorf("transfer complete before 8 bits")
+	}
+	mem.serial.step(4, &mem)
+	if hasBit(uint16(mem.readByte(0xff02)), 7) {
+		t.Errorf("transfer not complete after 8 bits")
+	}
+	if !hasBit(uint16(mem.io[0x0f]), uint16(serialInterrupt)) {
+		t.Errorf("serial interrupt not requested")
+	}
+	// no link cable: the bits received are all 1
+	if mem.readByte(0xff01) != 0xff {
+		t.Errorf("0x%02X in SB register, expected 0xFF", mem.readByte(0xff01))
+	}
+	if output := string(mem.serial.readOutput()); output != "P" {
+		t.Errorf("%q sent, expected %q", output, "P")
+	}
+	if len(mem.serial.readOutput()) != 0 {
+		t.Errorf("output not cleared once read")
+	}
+}
+
+func TestSerialExternalClock(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff01, 0x42)
+	mem.writeByte(0xff02, 0x80)
+	mem.serial.step(serialBitCycles*16, &mem)
+	if !hasBit(uint16(mem.readByte(0xff02)), 7) || mem.readByte(0xff01) != 0x42 {
+		t.Errorf("transfer with the external clock completed without a link cable")
+	}
+	if mem.io[0x0f] != 0 {
+		t.Errorf("serial interrupt requested without a link cable")
+	}
+}
+
+func TestSerialOutputLimit(t *testing.T) {
+	var mem Memory
+	for i := 0; i < serialOutputSize*3; i++ {
+		mem.writeByte(0xff01, byte(i))
+		mem.writeByte(0xff02, 0x81)
+		mem.serial.step(serialBitCycles*8, &mem)
+	}
+	if length := len(mem.serial.readOutput()); length > serialOutputSize {
+		t.Errorf("%d bytes kept in the output, expected at most %d", length, serialOutputSize)
+	}
+}
@@ -0,0 +1,133 @@
+package gb
+
+// Square wave channels 1 and 2, only channel 1 has a frequency sweep
+// see https://gbdev.io/pandocs/Audio_Registers.html
+type Square struct {
+	enabled bool
+	// the DAC is on when the upper 5 bits of NRx2 are not all 0
+	dac       bool
+	duty      byte
+	position  int
+	frequency uint16
+	// cycles left before the next duty step
+	timer    int
+	length   Length
+	envelope Envelope
+	sweep    Sweep
+}
+
+// Frequency sweep, set by NR10
+type Sweep struct {
+	period  byte
+	negate  bool
+	shift   byte
+	timer   byte
+	enabled bool
+	shadow  uint16
+}
+
+// Waveforms of the 12.5%, 25%, 50% and 75% duty cycles
+var dutyCycles [4][8]byte = [4][8]byte{
+	{0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 0},
+}
+
+// Write register NRx0-NRx4 of the channel
+func (square *Square) writeRegister(register int, value byte) {
+	switch register {
+	case 0:
+		square.sweep.period = (value >> 4) & 0x07
+		square.sweep.negate = hasBit(uint16(value), 3)
+		square.sweep.shift = value & 0x07
+	case 1:
+		square.duty = value >> 6
+		square.length.counter = 64 - int(value&0x3F)
+	case 2:
+		square.envelope.write(value)
+		square.dac = value&0xF8 != 0
+		if !square.dac {
+			square.enabled = false
+		}
+	case 3:
+		square.frequency = square.frequency&0x700 | uint16(value)
+	case 4:
+		square.frequency = square.frequency&0xFF | uint16(value&0x07)<<8
+		square.length.enabled = hasBit(uint16(value), 6)
+		if hasBit(uint16(value), 7) {
+			square.trigger()
+		}
+	}
+}
+
+func (square *Square) trigger() {
+	square.enabled = square.dac
+	square.length.trigger(64)
+	square.timer = (2048 - int(square.frequency)) * 4
+	square.envelope.trigger()
+	square.sweep.shadow = square.frequency
+	square.sweep.timer = square.sweep.period
+	if square.sweep.timer == 0 {
+		square.sweep.timer = 8
+	}
+	square.sweep.enabled = square.sweep.period != 0 || square.sweep.shift != 0
+	// the overflow check is done immediately
+	if square.sweep.shift != 0 {
+		square.sweepFrequency()
+	}
+}
+
+// Advance the channel by one cycle
+func (square *Square) step() {
+	square.timer--
+	if square.timer <= 0 {
+		square.timer = (2048 - int(square.frequency)) * 4
+		square.position = (square.position + 1) & 0x07
+	}
+}
+
+// Returns the channel volume, between 0 and 15
+func (square *Square) output() byte {
+	if !square.enabled {
+		return 0
+	}
+	return dutyCycles[square.duty][square.position] * square.envelope.volume
+}
+
+// Returns the next frequency of the sweep, disabling the channel on overflow
+func (square *Square) sweepFrequency() uint16 {
+	delta := square.sweep.shadow >> square.sweep.shift
+	frequency := square.sweep.shadow + delta
+	if square.sweep.negate {
+		frequency = square.sweep.shadow - delta
+	}
+	if frequency > 2047 {
+		square.enabled = false
+	}
+	return frequency
+}
+
+// Clock the frequency sweep, at 128 Hz
+func (square *Square) clockSweep() {
+	if square.sweep.timer > 0 {
+		square.sweep.timer--
+	}
+	if square.sweep.timer > 0 {
+		return
+	}
+	square.sweep.timer = square.sweep.period
+	if square.sweep.timer == 0 {
+		square.sweep.timer = 8
+	}
+	if !square.sweep.enabled || square.sweep.period == 0 {
+		return
+	}
+	frequency := square.sweepFrequency()
+	if frequency <= 2047 && square.sweep.shift != 0 {
+		square.sweep.shadow = frequency
+		square.frequency = frequency
+		// the new frequency is checked for overflow again
+		square.sweepFrequency()
+	}
+}
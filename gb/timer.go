@@ -0,0 +1,93 @@
+package gb
+
+// see https://gbdev.io/pandocs/Timer_and_Divider_Registers.html
+type Timer struct {
+	// internal 16 bit counter incremented every cycle, DIV is its upper byte
+	counter uint16
+	tima    byte
+	tma     byte
+	tac     byte
+	// cycles left before TIMA is reloaded from TMA after an overflow
+	reloading int
+}
+
+// Bit of the internal counter driving TIMA for each TAC clock select
+// (4096, 262144, 65536 and 16384 Hz)
+var timerBits [4]uint16 = [4]uint16{9, 3, 5, 7}
+
+// Returns the signal TIMA is incremented on: a falling edge of this signal increments TIMA
+func (timer *Timer) signal() bool {
+	return hasBit(uint16(timer.tac), 2) && hasBit(timer.counter, timerBits[timer.tac&0x03])
+}
+
+// Increment TIMA and schedule the TMA reload on overflow
+func (timer *Timer) increment() {
+	timer.tima++
+	if timer.tima == 0 {
+		// TIMA stays at 0 for one M-cycle before being reloaded
+		timer.reloading = 4
+	}
+}
+
+// Advance the timer by the given number of cycles
+func (timer *Timer) step(cycles int, mem *Memory) {
+	for i := 0; i < cycles; i++ {
+		if timer.reloading > 0 {
+			timer.reloading--
+			if timer.reloading == 0 {
+				timer.tima = timer.tma
+				mem.requestInterrupt(timerInterrupt)
+			}
+		}
+		before := timer.signal()
+		timer.counter++
+		if before && !timer.signal() {
+			timer.increment()
+		}
+	}
+}
+
+// Reset the internal counter, as done by writes to DIV and by STOP
+func (timer *Timer) resetDiv() {
+	before := timer.signal()
+	timer.counter = 0
+	// resetting the counter can produce a falling edge and increment TIMA
+	if before {
+		timer.increment()
+	}
+}
+
+func (timer *Timer) readByte(address uint16) byte {
+	switch address {
+	case 0xFF04:
+		return byte(timer.counter >> 8)
+	case 0xFF05:
+		return timer.tima
+	case 0xFF06:
+		return timer.tma
+	case 0xFF07:
+		// unused bits of TAC read as 1
+		return timer.tac | 0xF8
+	}
+	return 0xFF
+}
+
+func (timer *Timer) writeByte(address uint16, value byte) {
+	switch address {
+	case 0xFF04:
+		timer.resetDiv()
+	case 0xFF05:
+		// writing TIMA while a reload is pending cancels the reload
+		timer.tima = value
+		timer.reloading = 0
+	case 0xFF06:
+		timer.tma = value
+	case 0xFF07:
+		before := timer.signal()
+		timer.tac = value & 0x07
+		// disabling the timer or changing its clock can produce a falling edge
+		if before && !timer.signal() {
+			timer.increment()
+		}
+	}
+}
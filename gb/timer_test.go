@@ -0,0 +1,100 @@
+package gb
+
+import "testing"
+
+func TestDiv(t *testing.T) {
+	var mem Memory
+	mem.timer.step(256*3, &mem)
+	if mem.readByte(0xff04) != 3 {
+		t.Errorf("%d in DIV register, expected 3", mem.readByte(0xff04))
+	}
+	mem.writeByte(0xff04, 0x50)
+	if mem.readByte(0xff04) != 0 || mem.timer.counter != 0 {
+		t.Errorf("write to DIV did not reset the internal counter")
+	}
+}
+
+func TestTima(t *testing.T) {
+	var mem Memory
+	// enabled, 262144 Hz: TIMA increments every 16 cycles
+	mem.writeByte(0xff07, 0x05)
+	mem.timer.step(16*10, &mem)
+	if mem.readByte(0xff05) != 10 {
+		t.Errorf("%d in TIMA register, expected 10", mem.readByte(0xff05))
+	}
+	mem.writeByte(0xff07, 0x01)
+	mem.timer.step(16*10, &mem)
+	if mem.readByte(0xff05) != 10 {
+		t.Errorf("%d in TIMA register, expected disabled timer to stay at 10", mem.readByte(0xff05))
+	}
+}
+
+func TestTimaOverflow(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff06, 0xab)
+	mem.writeByte(0xff05, 0xff)
+	mem.writeByte(0xff07, 0x05)
+	mem.timer.step(16, &mem)
+	if mem.readByte(0xff05) != 0 {
+		t.Errorf("%d in TIMA register, expected 0 during the reload delay", mem.readByte(0xff05))
+	}
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(timerInterrupt)) {
+		t.Errorf("timer interrupt requested before the reload delay")
+	}
+	mem.timer.step(4, &mem)
+	if mem.readByte(0xff05) != 0xab {
+		t.Errorf("%d in TIMA register, expected TMA %d", mem.readByte(0xff05), 0xab)
+	}
+	if !hasBit(uint16(mem.readByte(0xff0f)), uint16(timerInterrupt)) {
+		t.Errorf("timer interrupt not requested")
+	}
+}
+
+func TestTimaOverflowCancelled(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff06, 0xab)
+	mem.writeByte(0xff05, 0xff)
+	mem.writeByte(0xff07, 0x05)
+	mem.timer.step(16, &mem)
+	mem.writeByte(0xff05, 0x12)
+	mem.timer.step(4, &mem)
+	if mem.readByte(0xff05) != 0x12 {
+		t.Errorf("%d in TIMA register, expected written value %d", mem.readByte(0xff05), 0x12)
+	}
+	if hasBit(uint16(mem.readByte(0xff0f)), uint16(timerInterrupt)) {
+		t.Errorf("timer interrupt requested after a cancelled reload")
+	}
+}
+
+func TestDivWriteGlitch(t *testing.T) {
+	var mem Memory
+	mem.writeByte(0xff07, 0x05)
+	// bit 3 of the internal counter is set, resetting it is a falling edge
+	mem.timer.step(8, &mem)
+	mem.writeByte(0xff04, 0)
+	if mem.readByte(0xff05) != 1 {
+		t.Errorf("%d in TIMA register, expected DIV write to increment it", mem.readByte(0xff05))
+	}
+}
+
+func TestTimerInstructionCycles(t *testing.T) {
+	emu, err := New(makeRom(0x00, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// run NOPs after the header, with TIMA counting every 16 cycles from a reset DIV
+	emu.cpu.pc = 0x150
+	emu.mem.writeByte(0xff04, 0)
+	emu.mem.writeByte(0xff05, 0)
+	emu.mem.writeByte(0xff07, 0x05)
+	cycles := 0
+	for i := 0; i < 1000; i++ {
+		cycles += emu.StepInstruction()
+	}
+	if cycles != 4000 {
+		t.Errorf("%d cycles for 1000 NOPs, expected 4000", cycles)
+	}
+	if emu.mem.readByte(0xff05) != 250 {
+		t.Errorf("%d in TIMA register, expected 250", emu.mem.readByte(0xff05))
+	}
+}
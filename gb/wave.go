@@ -0,0 +1,73 @@
+package gb
+
+// Wave channel 3, playing the 32 4-bit samples of wave RAM
+// see https://gbdev.io/pandocs/Audio_Registers.html
+type Wave struct {
+	enabled bool
+	// the DAC is on when bit 7 of NR30 is set
+	dac bool
+	// NR32 output level: mute, 100%, 50% or 25%
+	level     byte
+	position  int
+	sample    byte
+	frequency uint16
+	// cycles left before the next sample
+	timer  int
+	length Length
+}
+
+// Right shift of the samples for each output level
+var waveShifts [4]byte = [4]byte{4, 0, 1, 2}
+
+// Write register NR30-NR34
+func (wave *Wave) writeRegister(register int, value byte) {
+	switch register {
+	case 0:
+		wave.dac = hasBit(uint16(value), 7)
+		if !wave.dac {
+			wave.enabled = false
+		}
+	case 1:
+		wave.length.counter = 256 - int(value)
+	case 2:
+		wave.level = (value >> 5) & 0x03
+	case 3:
+		wave.frequency = wave.frequency&0x700 | uint16(value)
+	case 4:
+		wave.frequency = wave.frequency&0xFF | uint16(value&0x07)<<8
+		wave.length.enabled = hasBit(uint16(value), 6)
+		if hasBit(uint16(value), 7) {
+			wave.trigger()
+		}
+	}
+}
+
+func (wave *Wave) trigger() {
+	wave.enabled = wave.dac
+	wave.length.trigger(256)
+	wave.timer = (2048 - int(wave.frequency)) * 2
+	wave.position = 0
+}
+
+// Advance the channel by one cycle
+func (wave *Wave) step(ram *[16]byte) {
+	wave.timer--
+	if wave.timer <= 0 {
+		wave.timer = (2048 - int(wave.frequency)) * 2
+		wave.position = (wave.position + 1) & 0x1F
+		// samples are played upper nibble first
+		wave.sample = ram[wave.position/2]
+		if wave.position%2 == 0 {
+			wave.sample >>= 4
+		}
+		wave.sample &= 0x0F
+	}
+}
+
+// Returns the channel volume, between 0 and 15
+func (wave *Wave) output() byte {
+	if !wave.enabled {
+		return 0
+	}
+	return wave.sample >> waveShifts[wave.level]
+}
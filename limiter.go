@@ -0,0 +1,30 @@
+package main
+
+import "time"
+
+// Duration of a frame: 70224 cycles at 4194304 Hz, about 59.73 frames per second
+const frameDuration time.Duration = time.Duration(70224 * int64(time.Second) / 4194304)
+
+// Paces the frames to frameDuration when no audio output drives the emulation speed
+type Limiter struct {
+	// time the next frame is due
+	next  time.Time
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newLimiter() *Limiter {
+	return &Limiter{now: time.Now, sleep: time.Sleep}
+}
+
+// Wait until the next frame is due. When more than a frame late, after a
+// pause or a slow frame, the pace restarts from now instead of catching up.
+func (limiter *Limiter) wait() {
+	now := limiter.now()
+	if limiter.next.IsZero() || now.Sub(limiter.next) > frameDuration {
+		limiter.next = now
+	} else if limiter.next.After(now) {
+		limiter.sleep(limiter.next.Sub(now))
+	}
+	limiter.next = limiter.next.Add(frameDuration)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Returns a limiter on a fake clock, advanced by its sleeps
+func newTestLimiter(clock *time.Time, slept *time.Duration) *Limiter {
+	return &Limiter{
+		now: func() time.Time { return *clock },
+		sleep: func(duration time.Duration) {
+			*slept += duration
+			*clock = clock.Add(duration)
+		},
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	var slept time.Duration
+	limiter := newTestLimiter(&clock, &slept)
+	limiter.wait()
+	if slept != 0 {
+		t.Errorf("first frame waited %s, expected 0", slept)
+	}
+	// frames emulated in 1ms wait for the rest of the frame duration
+	for i := 0; i < 60; i++ {
+		clock = clock.Add(time.Millisecond)
+		limiter.wait()
+	}
+	if slept != 60*(frameDuration-time.Millisecond) {
+		t.Errorf("waited %s for 60 frames, expected %s", slept, 60*(frameDuration-time.Millisecond))
+	}
+	if frameDuration < 16740*time.Microsecond || frameDuration > 16750*time.Microsecond {
+		t.Errorf("%s frame duration, expected 59.73 frames per second", frameDuration)
+	}
+}
+
+func TestLimiterLate(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	var slept time.Duration
+	limiter := newTestLimiter(&clock, &slept)
+	limiter.wait()
+	// paused for a second: no frames are run without waiting to catch up
+	clock = clock.Add(time.Second)
+	limiter.wait()
+	clock = clock.Add(time.Millisecond)
+	limiter.wait()
+	if slept != frameDuration-time.Millisecond {
+		t.Errorf("waited %s after a pause, expected %s", slept, frameDuration-time.Millisecond)
+	}
+}
@@ -1,40 +1,105 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"time"
+
+	"example/gameboy/gb"
 )
 
 func main() {
-	var memory Memory
-	var cpu Register
-	var gpu Gpu
-	var display Display
-	memory.loadRom("roms/tetris")
-	defer display.close()
-	defer display.vramClose()
-	//var i int = 0
-	cpu.a = 0x01
-	cpu.flags = 0xb0
-	cpu.c = 0x13
-	cpu.e = 0xd8
-	cpu.h = 0x01
-	cpu.l = 0x4d
-	cpu.sp = 0xfffe
-	cpu.pc = 0x100
-	display.init()
-	display.initVramViewer()
-	//memory.writeByte(0xff44, 0x94)
-	for display.running {
-		//for cpu.pc != 0x2a6 {
-		//debug
-		if cpu.pc == 0x29a6 {
-			fmt.Println(cpu.pc)
+	os.Exit(run(os.Args[1:]))
+}
+
+// Run the emulator with the command line arguments args, returns the exit code
+func run(args []string) int {
+	options, err := parseOptions(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "cauca: %s\n", err)
+		return 2
+	}
+	rom, err := os.ReadFile(options.rom)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cauca: cannot load ROM: %s\n", err)
+		return 1
+	}
+	opts := []gb.Option{
+		gb.WithModel(options.model),
+		gb.WithSaveFile(gb.SavePath(options.rom, options.saveDir)),
+	}
+	if options.fifo {
+		opts = append(opts, gb.WithPixelFifo())
+	}
+	if options.bootRom != "" {
+		bootRom, err := os.ReadFile(options.bootRom)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cauca: cannot load boot ROM: %s\n", err)
+			return 1
+		}
+		opts = append(opts, gb.WithBootRom(bootRom))
+	}
+
+	var audio AudioOutput
+	if !options.headless && options.audio {
+		audio = newSdlAudio(48000)
+		if audio != nil {
+			defer audio.close()
+			opts = append(opts, gb.WithSampleRate(audio.outputRate()))
+		}
+	}
+	emu, err := gb.New(rom, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cauca: cannot load ROM %s: %s\n", options.rom, err)
+		return 1
+	}
+
+	var frontend Frontend
+	if options.headless {
+		frontend = newHeadless()
+	} else {
+		frontend, err = newSdlFrontend(options, emu, audio)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cauca: %s\n", err)
+			return 1
+		}
+	}
+	defer frontend.close()
+	emu.Connect(frontend, audio, frontend)
+	// the audio queue paces the emulation, the headless mode runs as fast as possible
+	var limiter *Limiter
+	if audio == nil && !options.headless {
+		limiter = newLimiter()
+	}
+	frames := 0
+	for frontend.handleEvents() && (options.frames == 0 || frames < options.frames) {
+		if frontend.isPaused() {
+			time.Sleep(10 * time.Millisecond)
+			continue
 		}
-		cpu.execute(memory.readByte(cpu.pc), &memory)
-		gpu.step(cpu, &memory)
-		display.display(gpu)
-		display.displayVram(gpu, memory)
-		//i++
+		if err := emu.RunFrame(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write save file: %s\n", err)
+		}
+		frontend.setRumble(emu.Rumble())
+		if limiter != nil {
+			limiter.wait()
+		}
+		frames++
+	}
+	status := 0
+	if options.screenshot != "" {
+		if err := writeScreenshot(options.screenshot, emu.FrameBuffer()); err != nil {
+			fmt.Fprintf(os.Stderr, "cauca: cannot write screenshot: %s\n", err)
+			status = 1
+		}
+	}
+	if err := emu.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write save file: %s\n", err)
+		return 1
 	}
-	//os.WriteFile("tile.bin", memory.vram[:], 0777)
+	return status
 }
@@ -0,0 +1,20 @@
+//go:build !sdl
+// +build !sdl
+
+package main
+
+import (
+	"errors"
+
+	"example/gameboy/gb"
+)
+
+// Built without the sdl tag: no audio output
+func newSdlAudio(sampleRate int) AudioOutput {
+	return nil
+}
+
+// Built without the sdl tag: only the headless frontend is available
+func newSdlFrontend(options Options, emu *gb.Emulator, audio AudioOutput) (Frontend, error) {
+	return nil, errors.New("built without SDL, rebuild with -tags sdl or use -headless")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"example/gameboy/gb"
+)
+
+// Command line options
+type Options struct {
+	rom     string
+	bootRom string
+	saveDir string
+	scale   int
+	model   gb.Model
+	// use the pixel FIFO renderer instead of the scanline renderer
+	fifo     bool
+	headless bool
+	debug    bool
+	paused   bool
+	audio    bool
+	// number of frames to run, 0 runs until the window is closed
+	frames int
+	// PNG file of the last frame, written when the emulator stops
+	screenshot string
+	// joypad buttons bound to SDL key and game controller button names,
+	// replacing the default bindings of these joypad buttons
+	keys              map[string]gb.Buttons
+	controllerButtons map[string]gb.Buttons
+}
+
+// Joypad button names, as given in the bindings
+var buttonNames map[string]gb.Buttons = map[string]gb.Buttons{
+	"right":  gb.ButtonRight,
+	"left":   gb.ButtonLeft,
+	"up":     gb.ButtonUp,
+	"down":   gb.ButtonDown,
+	"a":      gb.ButtonA,
+	"b":      gb.ButtonB,
+	"select": gb.ButtonSelect,
+	"start":  gb.ButtonStart,
+}
+
+const usage string = `Usage: cauca [options] ROM
+
+Runs the Game Boy ROM file ROM. Battery backed cartridge RAM is saved next to
+the ROM, or in the save directory, in a file with the .sav extension.
+
+Controls: arrows, X (A), Z (B), Backspace (Select), Enter (Start), P pauses,
+M mutes, - and = change the volume. Buttons are rebound with -keys and
+-controller, as comma separated BUTTON=NAME pairs, BUTTON being right, left,
+up, down, a, b, select or start and NAME an SDL key or game controller button
+name, e.g. -keys "a=K,b=J,start=Space" -controller "a=b,b=a".
+
+Options:
+`
+
+// Parse the command line arguments, flag.ErrHelp is returned when the help was
+// requested and written to output
+func parseOptions(args []string, output io.Writer) (Options, error) {
+	var options Options
+	flags := flag.NewFlagSet("cauca", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprint(output, usage)
+		flags.PrintDefaults()
+	}
+	flags.IntVar(&options.scale, "scale", 4, "window size, as a multiple of the 160x144 screen")
+	modelName := flags.String("model", "dmg", "hardware model: dmg0, dmg, mgb, sgb or cgb")
+	flags.StringVar(&options.bootRom, "bootrom", "", "boot ROM to run before the cartridge, instead of starting at 0x0100")
+	flags.StringVar(&options.saveDir, "savedir", "", "directory of the .sav files, instead of the ROM directory")
+	flags.BoolVar(&options.headless, "headless", false, "run without window nor audio")
+	flags.BoolVar(&options.debug, "debug", false, "open the debug windows (VRAM viewer)")
+	flags.BoolVar(&options.paused, "paused", false, "start paused, P resumes")
+	flags.BoolVar(&options.audio, "audio", true, "play audio")
+	flags.IntVar(&options.frames, "frames", 0, "stop after running this number of frames, 0 runs until the window is closed")
+	flags.StringVar(&options.screenshot, "screenshot", "", "write the last frame to this PNG file when stopping")
+	keys := flags.String("keys", "", "keyboard bindings, as BUTTON=KEY pairs")
+	controllerButtons := flags.String("controller", "", "game controller bindings, as BUTTON=CONTROLLER_BUTTON pairs")
+	ppu := flags.String("ppu", "scanline", "PPU renderer: scanline, or fifo for the slower cycle accurate pixel FIFO")
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+
+	if flags.NArg() != 1 {
+		return options, fmt.Errorf("expected one ROM file, got %d arguments (see -help)", flags.NArg())
+	}
+	options.rom = flags.Arg(0)
+	if options.scale < 1 || options.scale > 16 {
+		return options, fmt.Errorf("invalid scale %d, expected 1 to 16", options.scale)
+	}
+	if options.frames < 0 {
+		return options, fmt.Errorf("invalid number of frames %d", options.frames)
+	}
+	model, ok := gb.ParseModel(*modelName)
+	if !ok {
+		return options, fmt.Errorf("unknown model %q, expected dmg0, dmg, mgb, sgb or cgb", *modelName)
+	}
+	options.model = model
+	var err error
+	if options.keys, err = parseBindings(*keys); err != nil {
+		return options, fmt.Errorf("invalid -keys: %w", err)
+	}
+	if options.controllerButtons, err = parseBindings(*controllerButtons); err != nil {
+		return options, fmt.Errorf("invalid -controller: %w", err)
+	}
+	switch *ppu {
+	case "scanline":
+	case "fifo":
+		options.fifo = true
+	default:
+		return options, fmt.Errorf("unknown PPU renderer %q, expected scanline or fifo", *ppu)
+	}
+	return options, nil
+}
+
+// Parse comma separated BUTTON=NAME pairs, returns the joypad button bound to each name
+func parseBindings(value string) (map[string]gb.Buttons, error) {
+	if value == "" {
+		return nil, nil
+	}
+	bindings := make(map[string]gb.Buttons)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("expected BUTTON=NAME, got %q", pair)
+		}
+		button, ok := buttonNames[strings.ToLower(strings.TrimSpace(parts[0]))]
+		if !ok {
+			return nil, fmt.Errorf("unknown button %q, expected right, left, up, down, a, b, select or start", parts[0])
+		}
+		bindings[strings.TrimSpace(parts[1])] = button
+	}
+	return bindings, nil
+}
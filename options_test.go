@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"example/gameboy/gb"
+)
+
+func TestParseOptions(t *testing.T) {
+	var output bytes.Buffer
+	options, err := parseOptions([]string{"-scale", "2", "-model", "mgb", "-headless", "-audio=false", "-ppu", "fifo", "roms/zelda.gb"}, &output)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if options.rom != "roms/zelda.gb" || options.scale != 2 || options.model != gb.ModelMgb {
+		t.Errorf("wrong options %+v", options)
+	}
+	if !options.headless || options.audio || !options.fifo || options.debug || options.paused {
+		t.Errorf("wrong boolean options %+v", options)
+	}
+	options, err = parseOptions([]string{"-headless", "-frames", "600", "-screenshot", "out.png", "tetris.gb"}, &output)
+	if err != nil || options.frames != 600 || options.screenshot != "out.png" {
+		t.Errorf("wrong screenshot options %+v", options)
+	}
+	options, err = parseOptions([]string{"tetris.gb"}, &output)
+	if err != nil || options.scale != 4 || options.model != gb.ModelDmg || !options.audio || options.fifo || options.frames != 0 {
+		t.Errorf("wrong default options %+v", options)
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"a.gb", "b.gb"},
+		{"-scale", "0", "a.gb"},
+		{"-model", "gba", "a.gb"},
+		{"-ppu", "fast", "a.gb"},
+		{"-frames", "-1", "a.gb"},
+		{"-keys", "turbo=K", "a.gb"},
+		{"-keys", "a", "a.gb"},
+		{"-controller", "a=", "a.gb"},
+		{"-unknown", "a.gb"},
+	} {
+		var output bytes.Buffer
+		if _, err := parseOptions(args, &output); err == nil {
+			t.Errorf("no error for arguments %q", args)
+		}
+	}
+}
+
+func TestParseOptionsHelp(t *testing.T) {
+	var output bytes.Buffer
+	_, err := parseOptions([]string{"-help"}, &output)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("%v error, expected flag.ErrHelp", err)
+	}
+	if !strings.Contains(output.String(), "Usage: cauca") || !strings.Contains(output.String(), "-bootrom") {
+		t.Errorf("help does not describe the usage and options")
+	}
+}
+
+func TestParseBindings(t *testing.T) {
+	var output bytes.Buffer
+	options, err := parseOptions([]string{"-keys", "a=K, B=J,start=Left Shift,a=Space", "-controller", "a=b,b=a", "a.gb"}, &output)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := map[string]gb.Buttons{"K": gb.ButtonA, "J": gb.ButtonB, "Left Shift": gb.ButtonStart, "Space": gb.ButtonA}
+	if len(options.keys) != len(expected) {
+		t.Errorf("%v key bindings, expected %v", options.keys, expected)
+	}
+	for key, button := range expected {
+		if options.keys[key] != button {
+			t.Errorf("%v bound to key %q, expected %v", options.keys[key], key, button)
+		}
+	}
+	if options.controllerButtons["b"] != gb.ButtonA || options.controllerButtons["a"] != gb.ButtonB {
+		t.Errorf("wrong controller bindings %v", options.controllerButtons)
+	}
+	options, _ = parseOptions([]string{"a.gb"}, &output)
+	if options.keys != nil || options.controllerButtons != nil {
+		t.Errorf("bindings without -keys nor -controller")
+	}
+}
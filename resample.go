@@ -0,0 +1,51 @@
+package main
+
+// Maximum deviation of the resampling ratio from 1, small enough for the pitch
+// change to be inaudible
+const maxRateDelta float64 = 0.005
+
+// Linear resampler for interleaved stereo samples, used to slightly stretch or
+// shrink the audio stream so the audio queue stays close to its target fill
+type Resampler struct {
+	// position of the next output frame, relative to the previous frame
+	position float64
+	previous [2]float32
+}
+
+// Returns the resampling ratio bringing queued frames back to target frames:
+// above 1 when the queue runs low, below 1 when it fills up
+func rateRatio(queued int, target int) float64 {
+	delta := maxRateDelta * float64(target-queued) / float64(target)
+	if delta > maxRateDelta {
+		delta = maxRateDelta
+	} else if delta < -maxRateDelta {
+		delta = -maxRateDelta
+	}
+	return 1 + delta
+}
+
+// Returns about len(samples)*ratio samples, interpolated from samples
+func (resampler *Resampler) resample(samples []float32, ratio float64) []float32 {
+	frames := len(samples) / 2
+	if frames == 0 {
+		return nil
+	}
+	output := make([]float32, 0, int(float64(frames)*ratio+1)*2)
+	frame := func(index int) (float32, float32) {
+		if index < 0 {
+			return resampler.previous[0], resampler.previous[1]
+		}
+		return samples[index*2], samples[index*2+1]
+	}
+	for resampler.position < float64(frames) {
+		index := int(resampler.position)
+		t := float32(resampler.position - float64(index))
+		leftA, rightA := frame(index - 1)
+		leftB, rightB := frame(index)
+		output = append(output, leftA+(leftB-leftA)*t, rightA+(rightB-rightA)*t)
+		resampler.position += 1 / ratio
+	}
+	resampler.position -= float64(frames)
+	resampler.previous[0], resampler.previous[1] = frame(frames - 1)
+	return output
+}
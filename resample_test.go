@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestRateRatio(t *testing.T) {
+	if ratio := rateRatio(1000, 1000); ratio != 1 {
+		t.Errorf("%f ratio at the target fill, expected 1", ratio)
+	}
+	if ratio := rateRatio(500, 1000); ratio <= 1 {
+		t.Errorf("%f ratio with a low queue, expected above 1", ratio)
+	}
+	if ratio := rateRatio(5000, 1000); ratio != 1-maxRateDelta {
+		t.Errorf("%f ratio with a full queue, expected %f", ratio, 1-maxRateDelta)
+	}
+}
+
+func TestResample(t *testing.T) {
+	var resampler Resampler
+	samples := make([]float32, 200)
+	for i := range samples {
+		samples[i] = float32(i / 2)
+	}
+	output := resampler.resample(samples, 1)
+	if len(output) != len(samples) {
+		t.Fatalf("%d samples, expected %d", len(output), len(samples))
+	}
+	// the output is delayed by one frame
+	for i := 2; i < len(output); i++ {
+		if output[i] != samples[i-2] {
+			t.Fatalf("%f sample %d, expected %f", output[i], i, samples[i-2])
+		}
+	}
+	total := 0
+	for i := 0; i < 100; i++ {
+		total += len(resampler.resample(samples, 1.005)) / 2
+	}
+	if total < 10045 || total > 10055 {
+		t.Errorf("%d frames stretched from 10000, expected 10050", total)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"image/png"
+	"os"
+
+	"example/gameboy/gb"
+)
+
+// Write frame to the PNG file at path
+func writeScreenshot(path string, frame gb.Frame) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, frame.Image()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example/gameboy/gb"
+)
+
+func TestWriteScreenshot(t *testing.T) {
+	var frame gb.Frame
+	frame[5][7] = 3
+	path := filepath.Join(t.TempDir(), "out.png")
+	if err := writeScreenshot(path, frame); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("cannot decode screenshot: %s", err)
+	}
+	if img.Bounds().Dx() != gb.ScreenWidth || img.Bounds().Dy() != gb.ScreenHeight {
+		t.Errorf("%v screenshot bounds, expected 160x144", img.Bounds())
+	}
+	if r, _, _, _ := img.At(7, 5).RGBA(); r != 0 {
+		t.Errorf("pixel 7,5 is not black")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r != 0xffff {
+		t.Errorf("pixel 0,0 is not white")
+	}
+}
+
+func TestRunHeadlessScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	rom := make([]byte, 0x8000)
+	// JR -2 at the entry point
+	rom[0x100], rom[0x101] = 0x18, 0xfe
+	// header checksum of an empty header
+	rom[0x14d] = 0xe7
+	romPath := filepath.Join(dir, "loop.gb")
+	if err := os.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	screenshot := filepath.Join(dir, "out.png")
+	if status := run([]string{"-headless", "-frames", "2", "-screenshot", screenshot, romPath}); status != 0 {
+		t.Fatalf("exit code %d, expected 0", status)
+	}
+	if _, err := os.Stat(screenshot); err != nil {
+		t.Errorf("screenshot not written: %s", err)
+	}
+	if status := run([]string{"-headless", "-frames", "1", "-screenshot", filepath.Join(dir, "missing", "out.png"), romPath}); status != 1 {
+		t.Errorf("exit code %d when the screenshot cannot be written, expected 1", status)
+	}
+}